@@ -1,11 +1,17 @@
 package gounter
 
 import (
+	"context"
 	"math"
 	"sync"
 	"sync/atomic"
+	"time"
+	"unicode/utf8"
 )
 
+// maxExemplarRunes is the max length of exemplar labels in OpenMetrics.
+const maxExemplarRunes = 128
+
 // Counter supports increasing and decreasing counter internal value.
 // Only responsible for counting, without any additional content.
 //
@@ -19,6 +25,39 @@ type Counter struct {
 	noCopy noCopy
 
 	bits uint64
+
+	// waiters of WaitUntil and Notify.
+	waiting int32
+	waiters []*counterWaiter
+	waitMux sync.Mutex
+
+	// exemplar is the *Exemplar of the last AddWithExemplar.
+	exemplar atomic.Value
+}
+
+// Exemplar is a reference to data outside the counter, like a trace ID,
+// attached to a change of Counter and exposed by OpenMetrics.
+type Exemplar struct {
+	Labels    map[string]string
+	Value     float64
+	Timestamp time.Time
+}
+
+// counterWaiter waits for the value of Counter.
+// released is set before ready is closed by ReleaseCounter.
+type counterWaiter struct {
+	cond     func(float64) bool
+	ready    chan struct{}
+	released bool
+}
+
+// err returns ErrCounterReleased if the waiter is woken by ReleaseCounter.
+func (w *counterWaiter) err() error {
+	if w.released {
+		return ErrCounterReleased
+	}
+
+	return nil
 }
 
 // counterPool is a pool for counter.
@@ -34,17 +73,21 @@ func AcquireCounter() *Counter {
 }
 
 // ReleaseCounter releases a Counter Pointer.
+// Pending waiters of WaitUntil and Notify are woken up and dropped.
 func ReleaseCounter(c *Counter) {
 	if c == nil {
 		return
 	}
 	c.reset()
+	c.releaseWaiters()
 	counterPool.Put(c)
 }
 
 // reset Counter to release.
 func (c *Counter) reset() {
 	atomic.StoreUint64(&c.bits, 0)
+	c.exemplar.Store((*Exemplar)(nil))
+	c.changed(0)
 }
 
 // Get returns a number.
@@ -84,6 +127,7 @@ func (c *Counter) Set(value float64) bool {
 		oldBits := atomic.LoadUint64(&c.bits)
 		newBits := math.Float64bits(value)
 		if atomic.CompareAndSwapUint64(&c.bits, oldBits, newBits) {
+			c.changed(value)
 			return true
 		}
 	}
@@ -98,11 +142,41 @@ func (c *Counter) Add(delta float64) bool {
 		newVal := math.Float64frombits(oldBits) + delta
 		newBits := math.Float64bits(newVal)
 		if atomic.CompareAndSwapUint64(&c.bits, oldBits, newBits) {
+			c.changed(newVal)
 			return true
 		}
 	}
 }
 
+// AddWithExemplar is same as Add,
+// and attaches an exemplar with the labels, the delta and the current time.
+// The exemplar is dropped if the labels are longer than 128 runes in total.
+// Counter always returns true.
+func (c *Counter) AddWithExemplar(delta float64, labels map[string]string) bool {
+	ok := c.Add(delta)
+
+	n := 0
+	for name, value := range labels {
+		n += utf8.RuneCountInString(name) + utf8.RuneCountInString(value)
+	}
+
+	if n <= maxExemplarRunes {
+		c.exemplar.Store(&Exemplar{
+			Labels:    labels,
+			Value:     delta,
+			Timestamp: time.Now(),
+		})
+	}
+
+	return ok
+}
+
+// Exemplar returns the exemplar of the last AddWithExemplar, nil if none.
+func (c *Counter) Exemplar() *Exemplar {
+	e, _ := c.exemplar.Load().(*Exemplar)
+	return e
+}
+
 // Sub decreases the counter number.
 // Counter always returns true.
 func (c *Counter) Sub(delta float64) bool {
@@ -133,8 +207,124 @@ func (c *Counter) CopyTo(d interface{}) (ok bool, err error) {
 		oldVal1 := atomic.LoadUint64(&dst.bits)
 		val1 := atomic.LoadUint64(&c.bits)
 		if atomic.CompareAndSwapUint64(&dst.bits, oldVal1, val1) {
+			dst.changed(math.Float64frombits(val1))
 			ok = true
 			return
 		}
 	}
 }
+
+// changed wakes the waiters whose condition is met by val,
+// the value written by the change.
+// Nobody waiting, it does not take the lock.
+func (c *Counter) changed(val float64) {
+	if atomic.LoadInt32(&c.waiting) == 0 {
+		return
+	}
+
+	c.waitMux.Lock()
+	defer c.waitMux.Unlock()
+
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.cond(val) {
+			atomic.AddInt32(&c.waiting, -1)
+			close(w.ready)
+			continue
+		}
+		waiters = append(waiters, w)
+	}
+
+	// clear the tail for gc
+	for i := len(waiters); i < len(c.waiters); i++ {
+		c.waiters[i] = nil
+	}
+	c.waiters = waiters
+}
+
+// releaseWaiters wakes and drops all waiters, for ReleaseCounter.
+func (c *Counter) releaseWaiters() {
+	c.waitMux.Lock()
+	defer c.waitMux.Unlock()
+
+	for i, w := range c.waiters {
+		w.released = true
+		close(w.ready)
+		c.waiters[i] = nil
+	}
+	c.waiters = c.waiters[:0]
+	atomic.StoreInt32(&c.waiting, 0)
+}
+
+// wait registers a waiter with the condition.
+// If the condition is already met, the waiter is ready.
+func (c *Counter) wait(cond func(float64) bool) *counterWaiter {
+	w := &counterWaiter{cond: cond, ready: make(chan struct{})}
+
+	c.waitMux.Lock()
+	defer c.waitMux.Unlock()
+
+	// count before checking, so a concurrent Add can not miss us
+	atomic.AddInt32(&c.waiting, 1)
+	if cond(c.Real()) {
+		atomic.AddInt32(&c.waiting, -1)
+		close(w.ready)
+		return w
+	}
+
+	c.waiters = append(c.waiters, w)
+	return w
+}
+
+// unwait removes the waiter, if it is not ready.
+func (c *Counter) unwait(w *counterWaiter) {
+	c.waitMux.Lock()
+	defer c.waitMux.Unlock()
+
+	for i, ww := range c.waiters {
+		if ww == w {
+			atomic.AddInt32(&c.waiting, -1)
+			last := len(c.waiters) - 1
+			copy(c.waiters[i:], c.waiters[i+1:])
+			c.waiters[last] = nil
+			c.waiters = c.waiters[:last]
+			return
+		}
+	}
+}
+
+// WaitUntil blocks until cond returns true for the real value of the counter,
+// or the context is done.
+// It returns ErrCounterReleased if the Counter is released by ReleaseCounter.
+// cond is called on every change while waiting, under a lock, keep it cheap.
+func (c *Counter) WaitUntil(ctx context.Context, cond func(float64) bool) error {
+	w := c.wait(cond)
+
+	select {
+	case <-w.ready:
+		return w.err()
+	case <-ctx.Done():
+	}
+
+	c.unwait(w)
+
+	select {
+	case <-w.ready:
+		// ready before removed
+		return w.err()
+	default:
+		return ctx.Err()
+	}
+}
+
+// Notify returns a channel, it is closed when the real value of the counter
+// reaches the threshold (greater than or equal to),
+// or when the Counter is released by ReleaseCounter.
+// The channel is never closed if neither happens.
+func (c *Counter) Notify(threshold float64) <-chan struct{} {
+	w := c.wait(func(val float64) bool {
+		return val >= threshold
+	})
+
+	return w.ready
+}
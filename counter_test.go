@@ -1,6 +1,7 @@
 package gounter
 
 import (
+	"context"
 	"math"
 	"math/rand"
 	"sync/atomic"
@@ -67,6 +68,138 @@ func TestReset(t *testing.T) {
 	}
 }
 
+func TestCounterWaitUntil(t *testing.T) {
+	t.Parallel()
+
+	c := AcquireCounter()
+	defer ReleaseCounter(c)
+
+	workers := 50
+	done := make(chan error)
+	go func() {
+		done <- c.WaitUntil(context.Background(), func(v float64) bool {
+			return v >= float64(workers)
+		})
+	}()
+
+	for i := 0; i < workers; i++ {
+		go c.Inc()
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("should be nil, but %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+
+	// already met
+	if err := c.WaitUntil(context.Background(), func(v float64) bool { return v > 0 }); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+
+	// cancel
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := c.WaitUntil(ctx, func(v float64) bool { return v < 0 })
+	if err != context.DeadlineExceeded {
+		t.Fatalf("should be %v, but %v", context.DeadlineExceeded, err)
+	}
+	if n := atomic.LoadInt32(&c.waiting); n != 0 {
+		t.Fatalf("waiting should be %d, but %d", 0, n)
+	}
+}
+
+func TestCounterNotify(t *testing.T) {
+	t.Parallel()
+
+	c := AcquireCounter()
+	defer ReleaseCounter(c)
+
+	ch := c.Notify(10)
+
+	c.Add(9)
+	select {
+	case <-ch:
+		t.Fatal("should not be notified")
+	default:
+	}
+
+	c.Set(10)
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+
+	// MaxCounter changes its Counter
+	m := AcquireMaxCounter(5)
+	defer ReleaseMaxCounter(m)
+
+	ch = m.counter.Notify(5)
+	for i := 0; i < 5; i++ {
+		m.Inc()
+	}
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestCounterReleaseWaiters(t *testing.T) {
+	t.Parallel()
+
+	c := &Counter{}
+	ch := c.Notify(5)
+
+	done := make(chan error)
+	go func() {
+		done <- c.WaitUntil(context.Background(), func(v float64) bool { return v >= 5 })
+	}()
+	for atomic.LoadInt32(&c.waiting) != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ReleaseCounter(c)
+
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	select {
+	case err := <-done:
+		if err != ErrCounterReleased {
+			t.Fatalf("should be %v, but %v", ErrCounterReleased, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	if n := atomic.LoadInt32(&c.waiting); n != 0 || len(c.waiters) != 0 {
+		t.Fatalf("waiters should be dropped, but %d %d", n, len(c.waiters))
+	}
+}
+
+func TestCounterChangedValue(t *testing.T) {
+	t.Parallel()
+
+	c := AcquireCounter()
+	defer ReleaseCounter(c)
+
+	ch := c.Notify(5)
+
+	// crossed and back before the waiters are checked
+	c.changed(5)
+	select {
+	case <-ch:
+	default:
+		t.Fatal("should be notified")
+	}
+}
+
 func testNewCounter(t *testing.T) {
 	for i := 0; i < 10; i++ {
 		c := AcquireCounter()
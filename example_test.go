@@ -82,3 +82,13 @@ func ExampleNewLabelCounterWithMax() {
 	counter := NewLabelCounterWithMax(50)
 	counter.Add("a", 10)
 }
+
+func ExampleNewLabelCounterFunc() {
+	counter := NewLabelCounterFunc[*MaxCounter](func(label string) *MaxCounter {
+		if label == "vip" {
+			return AcquireMaxCounter(100)
+		}
+		return AcquireMaxCounter(10)
+	}, ReleaseMaxCounter)
+	counter.Add("vip", 50)
+}
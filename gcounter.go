@@ -5,6 +5,22 @@ import "errors"
 var (
 	ErrSameCounterPointer   = errors.New("can not copy same counter")
 	ErrDifferentCounterType = errors.New("can not copy different type counter")
+	ErrLabelValuesCount     = errors.New("wrong number of label values")
+	ErrUnknownDimension     = errors.New("unknown label dimension")
+	ErrInvalidDimension     = errors.New("empty or duplicate label dimension")
+	ErrLabelLimit           = errors.New("label limit reached")
+	ErrLabelCounterClosed   = errors.New("label counter is closed")
+	ErrCounterReleased      = errors.New("counter is released")
+
+	ErrInvalidMetricName       = errors.New("invalid metric name")
+	ErrInvalidLabelName        = errors.New("invalid label name")
+	ErrInvalidMetricUnit       = errors.New("invalid metric unit")
+	ErrMetricAlreadyRegistered = errors.New("metric already registered")
+	ErrUnsupportedCounter      = errors.New("unsupported counter type")
+
+	ErrInvalidEncoding    = errors.New("invalid counter encoding")
+	ErrUnsupportedVersion = errors.New("unsupported counter encoding version")
+	ErrCorruptSnapshot    = errors.New("corrupt snapshot")
 )
 
 type Gounter interface {
@@ -20,6 +36,16 @@ type Gounter interface {
 	CopyTo(interface{}) (bool, error)
 }
 
+// realOf returns Real() if the Gounter has it, else Get(),
+// Get of most Gounters clamps negative values.
+func realOf(c Gounter) float64 {
+	if r, ok := c.(interface{ Real() float64 }); ok {
+		return r.Real()
+	}
+
+	return c.Get()
+}
+
 // LabelGounter gounter with label
 type LabelGounter[T Gounter] interface {
 	Get(string) (float64, T)
@@ -0,0 +1,173 @@
+package gounter
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+)
+
+// Int64Counter is an exact integer counter.
+// Counter stores float64, so it loses precision after 2^53,
+// Int64Counter stores int64 and never rounds.
+//
+// The float64 methods of Gounter return false and change nothing
+// if the given value is not an integer in the range of int64.
+// Like Counter, `Get()` and `GetInt()` return 0 when the value is negative.
+//
+// Copying is prohibited. Please acquire new object.
+type Int64Counter struct {
+	noCopy noCopy
+
+	val int64
+}
+
+// int64CounterPool is a pool for Int64Counter.
+var int64CounterPool = &sync.Pool{
+	New: func() any {
+		return &Int64Counter{}
+	},
+}
+
+// AcquireInt64Counter return a Int64Counter Pointer.
+func AcquireInt64Counter() *Int64Counter {
+	return int64CounterPool.Get().(*Int64Counter)
+}
+
+// ReleaseInt64Counter releases a Int64Counter Pointer.
+func ReleaseInt64Counter(c *Int64Counter) {
+	if c == nil {
+		return
+	}
+	c.reset()
+	int64CounterPool.Put(c)
+}
+
+// reset Int64Counter to release.
+func (c *Int64Counter) reset() {
+	atomic.StoreInt64(&c.val, 0)
+}
+
+// GetInt returns a number.
+// When the counter value is negative, it returns 0.
+func (c *Int64Counter) GetInt() int64 {
+	val := c.RealInt()
+	if val < 0 {
+		return 0
+	}
+
+	return val
+}
+
+// RealInt returns a number in counter.
+func (c *Int64Counter) RealInt() int64 {
+	return atomic.LoadInt64(&c.val)
+}
+
+// Get returns GetInt as float64.
+func (c *Int64Counter) Get() float64 {
+	return float64(c.GetInt())
+}
+
+// Real returns RealInt as float64.
+func (c *Int64Counter) Real() float64 {
+	return float64(c.RealInt())
+}
+
+// SetInt sets the value of the counter.
+// Int64Counter always returns true.
+func (c *Int64Counter) SetInt(value int64) bool {
+	atomic.StoreInt64(&c.val, value)
+	return true
+}
+
+// AddInt increases the counter number.
+// Decreasing use negative number.
+// Int64Counter always returns true.
+func (c *Int64Counter) AddInt(delta int64) bool {
+	atomic.AddInt64(&c.val, delta)
+	return true
+}
+
+// SubInt decreases the counter number.
+// Int64Counter always returns true.
+func (c *Int64Counter) SubInt(delta int64) bool {
+	return c.AddInt(-delta)
+}
+
+// toInt64 converts v to int64,
+// false if v is not an integer in the range of int64.
+func toInt64(v float64) (int64, bool) {
+	// -2^63 is exact in float64, 2^63 is out of range
+	if v != math.Trunc(v) || v < math.MinInt64 || v >= -math.MinInt64 {
+		return 0, false
+	}
+
+	return int64(v), true
+}
+
+// Set sets the value of the counter.
+// It returns false if the value is not an integer in the range of int64.
+func (c *Int64Counter) Set(value float64) bool {
+	n, ok := toInt64(value)
+	if !ok {
+		return false
+	}
+
+	return c.SetInt(n)
+}
+
+// Add increases the counter number.
+// It returns false if the delta is not an integer in the range of int64.
+func (c *Int64Counter) Add(delta float64) bool {
+	n, ok := toInt64(delta)
+	if !ok {
+		return false
+	}
+
+	return c.AddInt(n)
+}
+
+// Sub decreases the counter number.
+// It returns false if the delta is not an integer in the range of int64.
+func (c *Int64Counter) Sub(delta float64) bool {
+	n, ok := toInt64(delta)
+	if !ok {
+		return false
+	}
+
+	return c.SubInt(n)
+}
+
+// Inc increases the counter by 1.
+// Int64Counter always returns true.
+func (c *Int64Counter) Inc() bool {
+	return c.AddInt(1)
+}
+
+// Dec decreases the counter by 1.
+// Int64Counter always returns true.
+func (c *Int64Counter) Dec() bool {
+	return c.AddInt(-1)
+}
+
+// Reset resets this Int64Counter.
+func (c *Int64Counter) Reset() {
+	c.reset()
+}
+
+// CopyTo copies a Int64Counter to other Int64Counter.
+func (c *Int64Counter) CopyTo(d interface{}) (ok bool, err error) {
+	dst, can := d.(*Int64Counter)
+	if !can {
+		err = ErrDifferentCounterType
+		return
+	}
+
+	if c == dst {
+		err = ErrSameCounterPointer
+		return
+	}
+
+	ok = dst.SetInt(c.RealInt())
+	return
+}
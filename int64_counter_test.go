@@ -0,0 +1,137 @@
+package gounter
+
+import (
+	"math"
+	"sync"
+	"testing"
+)
+
+func TestInt64CounterReleaseNil(t *testing.T) {
+	ReleaseInt64Counter(nil)
+}
+
+func TestInt64CounterChange(t *testing.T) {
+	t.Parallel()
+
+	testInt64CounterIncAndDec(t)
+
+	testGo(t, testInt64CounterIncAndDec, 10)
+	testGo(t, testInt64CounterIncAndDec, 100)
+}
+
+func TestInt64CounterExact(t *testing.T) {
+	t.Parallel()
+
+	c := AcquireInt64Counter()
+	defer ReleaseInt64Counter(c)
+
+	// float64 can not represent 2^53 + 1
+	var big int64 = 1 << 53
+	c.SetInt(big)
+	c.Inc()
+
+	if v := c.GetInt(); v != big+1 {
+		t.Fatalf("should be %d, but %d", big+1, v)
+	}
+
+	// not an integer in the range of int64, nothing changed
+	c.Set(1)
+	for _, v := range []float64{0.5, -1.9, math.NaN(), math.Inf(1), math.Inf(-1), 1 << 63, -1 << 64} {
+		if c.Set(v) {
+			t.Fatalf("Set(%v) should be false, but true", v)
+		}
+		if c.Add(v) {
+			t.Fatalf("Add(%v) should be false, but true", v)
+		}
+		if c.Sub(v) {
+			t.Fatalf("Sub(%v) should be false, but true", v)
+		}
+	}
+	if v := c.GetInt(); v != 1 {
+		t.Fatalf("should be %d, but %d", 1, v)
+	}
+
+	// -2^63 is in range
+	if !c.Add(-1 << 63) {
+		t.Fatalf("should be true, but false")
+	}
+	c.SubInt(-1 << 63)
+
+	c.SubInt(3)
+	if v := c.GetInt(); v != 0 {
+		t.Fatalf("should be %d, but %d", 0, v)
+	}
+	if v := c.RealInt(); v != -2 {
+		t.Fatalf("should be %d, but %d", -2, v)
+	}
+	if v := c.Real(); v != -2 {
+		t.Fatalf("should be %d, but %f", -2, v)
+	}
+
+	c.Reset()
+	if v := c.Get(); v != 0 {
+		t.Fatalf("should be %d, but %f", 0, v)
+	}
+}
+
+func TestInt64CounterCopyTo(t *testing.T) {
+	t.Parallel()
+
+	c1 := AcquireInt64Counter()
+	c2 := AcquireInt64Counter()
+	defer ReleaseInt64Counter(c1)
+	defer ReleaseInt64Counter(c2)
+
+	c1.AddInt(7)
+
+	ok, err := c1.CopyTo(c1)
+	if ok || err != ErrSameCounterPointer {
+		t.Fatalf("same counter should err, but %v", err)
+	}
+
+	ok, err = c1.CopyTo(1)
+	if ok || err != ErrDifferentCounterType {
+		t.Fatalf("different counter should err, but %v", err)
+	}
+
+	ok, err = c1.CopyTo(c2)
+	if !ok || err != nil {
+		t.Fatalf("counter should be copied, but err: %v", err)
+	}
+	if v := c2.GetInt(); v != 7 {
+		t.Fatalf("should be %d, but %d", 7, v)
+	}
+}
+
+func TestLabelCounterWithInt64(t *testing.T) {
+	t.Parallel()
+
+	labels := testGenerateLabels()
+
+	c := NewLabelCounterInt64()
+	testLabelCounterIncDec(labels, 1000, c, t)
+}
+
+func testInt64CounterIncAndDec(t *testing.T) {
+	c := AcquireInt64Counter()
+	defer ReleaseInt64Counter(c)
+
+	wg := sync.WaitGroup{}
+	wg.Add(200)
+	for i := 0; i < 100; i++ {
+		go func() {
+			c.Inc()
+			wg.Done()
+		}()
+		go func() {
+			c.Dec()
+			wg.Done()
+		}()
+	}
+
+	wg.Wait()
+
+	if v := c.GetInt(); v != 0 {
+		t.Fatalf("should be %d, but %d", 0, v)
+	}
+}
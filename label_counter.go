@@ -1,32 +1,95 @@
 package gounter
 
 import (
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowLabel is the default label which new labels are folded into,
+// with LabelLimitOverflow.
+const OverflowLabel = "__other__"
+
+// LabelLimitPolicy decides what LabelCounter does
+// with a new label when the label limit is reached.
+type LabelLimitPolicy uint32
+
+const (
+	// LabelLimitReject rejects the new label, operations on it fail.
+	LabelLimitReject LabelLimitPolicy = iota
+	// LabelLimitOverflow folds the new label into the overflow label.
+	LabelLimitOverflow
+	// LabelLimitEvict removes the least-recently-updated label for the new label.
+	LabelLimitEvict
 )
 
 // LabelCounter is a structure that combines Counter and Label relationships,
 // and has the characteristics of both Map and Counter.
 // It cannot be copied directly.
+//
+// When a label is removed, expired, evicted or reset, its counter is released
+// using the rel function. Operations of LabelCounter on the counter are finished
+// before it is released, but counters returned to callers must not be used after that.
 type LabelCounter[T Gounter] struct {
 	noCopy noCopy
 
 	value   []T
 	labels  map[string]int
 	entries map[int]string
-	acq     func() T
+	acq     func(label string) T
 	rel     func(T)
 	mux     sync.RWMutex
+
+	observers []Observer
+
+	// sorted is the labels in order, for prefix queries.
+	sorted []string
+
+	// touched is the last update time (UnixNano) of each label,
+	// updated only when it is tracked.
+	touched []int64
+	track   bool
+
+	// created is the creation time (UnixNano) of each label.
+	created []int64
+
+	limit         int
+	limitPolicy   LabelLimitPolicy
+	overflowLabel string
+	dropped       uint64
+
+	ttl      time.Duration
+	labelTTL map[string]time.Duration
+	clock    func() time.Time
+	janitor  chan struct{}
+
+	closed bool
 }
 
 // NewLabelCounter creates and returns a new LabelCounter,
 // which is a generic struct that stores the mapping between values and labels.
 func NewLabelCounter[T Gounter](acq func() T, rel func(T)) *LabelCounter[T] {
+	return NewLabelCounterFunc[T](func(string) T {
+		return acq()
+	}, rel)
+}
+
+// NewLabelCounterFunc is same as NewLabelCounter,
+// but the acquire function receives the label of the new counter.
+func NewLabelCounterFunc[T Gounter](acq func(label string) T, rel func(T)) *LabelCounter[T] {
 	return &LabelCounter[T]{
 		value:   make([]T, 0),
 		labels:  make(map[string]int),
 		entries: make(map[int]string),
 		acq:     acq,
 		rel:     rel,
+
+		touched:       make([]int64, 0),
+		created:       make([]int64, 0),
+		overflowLabel: OverflowLabel,
+		labelTTL:      make(map[string]time.Duration),
+		clock:         time.Now,
 	}
 }
 
@@ -36,6 +99,28 @@ func NewLabelCounterNormal() *LabelCounter[*Counter] {
 	return NewLabelCounter[*Counter](AcquireCounter, ReleaseCounter)
 }
 
+// NewLabelCounterSharded returns a new LabelCounter with ShardedCounter as the underlying type.
+// It uses AcquireShardedCounter and ReleaseShardedCounter as the acquire and release functions.
+func NewLabelCounterSharded() *LabelCounter[*ShardedCounter] {
+	return NewLabelCounter[*ShardedCounter](AcquireShardedCounter, ReleaseShardedCounter)
+}
+
+// NewLabelCounterInt64 returns a new LabelCounter with Int64Counter as the underlying type.
+// It uses AcquireInt64Counter and ReleaseInt64Counter as the acquire and release functions.
+func NewLabelCounterInt64() *LabelCounter[*Int64Counter] {
+	return NewLabelCounter[*Int64Counter](AcquireInt64Counter, ReleaseInt64Counter)
+}
+
+// NewLabelCounterTyped returns a new LabelCounter with a TypedCounter under GounterAdapter.
+func NewLabelCounterTyped[N Number]() *LabelCounter[*GounterAdapter[N]] {
+	acq := func() *GounterAdapter[N] {
+		return AsGounter[N](NewTypedCounter[N]())
+	}
+	rel := func(*GounterAdapter[N]) {}
+
+	return NewLabelCounter[*GounterAdapter[N]](acq, rel)
+}
+
 // NewLabelCounterWithMax returns a new LabelCounter with MaxCounter as the underlying type.
 // It uses AcquireMaxCounter and ReleaseMaxCounter as the acquire and release functions for MaxCounter.
 // The max parameter is the maximum value for the MaxCounter.
@@ -47,6 +132,52 @@ func NewLabelCounterWithMax(max float64) *LabelCounter[*MaxCounter] {
 	return NewLabelCounter[*MaxCounter](acq, ReleaseMaxCounter)
 }
 
+// NewLabelCounterWithMaxFunc returns a new LabelCounter with MaxCounter as the underlying type.
+// The max of each MaxCounter is returned by maxOf for its label.
+func NewLabelCounterWithMaxFunc(maxOf func(label string) float64) *LabelCounter[*MaxCounter] {
+	acq := func(label string) *MaxCounter {
+		return AcquireMaxCounter(maxOf(label))
+	}
+
+	return NewLabelCounterFunc[*MaxCounter](acq, ReleaseMaxCounter)
+}
+
+// SetLabelMax sets the max of the MaxCounter of the label,
+// the MaxCounter is created if not exist.
+// It returns false if the label can not be created,
+// the max of the overflow label is not changed for a folded label.
+func SetLabelMax(counter *LabelCounter[*MaxCounter], label string, max float64) bool {
+	counter.mux.Lock()
+	defer counter.mux.Unlock()
+
+	c, idx := counter.createLabel(label)
+	if idx == -1 || counter.entries[idx] != label {
+		return false
+	}
+
+	c.SetMax(max)
+	return true
+}
+
+// NewLabelCounterWithRange returns a new LabelCounter with MaxCounter as the underlying type.
+// Each MaxCounter is bounded by min and max.
+func NewLabelCounterWithRange(min, max float64) *LabelCounter[*MaxCounter] {
+	acq := func() *MaxCounter {
+		return AcquireRangeCounter(min, max)
+	}
+
+	return NewLabelCounter[*MaxCounter](acq, ReleaseMaxCounter)
+}
+
+// Observe adds observers, they are notified when labels are created or removed.
+// Observers are called under the lock of LabelCounter, do not call back into it.
+func (counter *LabelCounter[T]) Observe(observers ...Observer) {
+	counter.mux.Lock()
+	defer counter.mux.Unlock()
+
+	counter.observers = append(counter.observers, observers...)
+}
+
 // RemoveLabel removes the label and its associated counter value from the LabelCounter.
 // It returns true if the label was found and removed, false otherwise.
 // It also releases the counter value using the rel function.
@@ -54,6 +185,12 @@ func (counter *LabelCounter[T]) RemoveLabel(label string) (ok bool) {
 	counter.mux.Lock()
 	defer counter.mux.Unlock()
 
+	return counter.removeLabel(label)
+}
+
+// removeLabel is RemoveLabel, the lock must be held.
+// The last label is moved to the index of the removed label.
+func (counter *LabelCounter[T]) removeLabel(label string) (ok bool) {
 	index, ok := counter.labels[label]
 	if !ok {
 		// not found
@@ -81,15 +218,28 @@ func (counter *LabelCounter[T]) RemoveLabel(label string) (ok bool) {
 		cc := counter.value[lastIdx]
 		// replace the current index with the last index in the value slice
 		counter.value[index] = cc
+		counter.touched[index] = counter.touched[lastIdx]
+		counter.created[index] = counter.created[lastIdx]
 		// update the labels map with the new index for the last label
 		counter.labels[lastLabel] = index
-		// delete the last index from the entries map
-		delete(counter.entries, lastIdx)
+		// update the entries map with the last label for the new index
+		counter.entries[index] = lastLabel
 	}
 
 	// remove label
 	counter.value = counter.value[:lastIdx]
+	counter.touched = counter.touched[:lastIdx]
+	counter.created = counter.created[:lastIdx]
+	if i := sort.SearchStrings(counter.sorted, label); i < len(counter.sorted) && counter.sorted[i] == label {
+		counter.sorted = append(counter.sorted[:i], counter.sorted[i+1:]...)
+	}
 	delete(counter.labels, label)
+	delete(counter.entries, lastIdx)
+	delete(counter.labelTTL, label)
+
+	for _, observer := range counter.observers {
+		observer.OnLabelRemoved(label)
+	}
 
 	return true
 }
@@ -99,116 +249,487 @@ func (counter *LabelCounter[T]) RemoveLabel(label string) (ok bool) {
 // It also stores the label and its index in the labels and entries maps.
 // Just use in getLabel.
 func (counter *LabelCounter[T]) newLabel(label string) (c T, idx int) {
-	c = counter.acq()
+	c = counter.acq(label)
 
+	now := counter.now()
 	counter.value = append(counter.value, c)
+	counter.touched = append(counter.touched, now)
+	counter.created = append(counter.created, now)
 	idx = len(counter.value) - 1
 	counter.labels[label] = idx
 	counter.entries[idx] = label
 
+	i := sort.SearchStrings(counter.sorted, label)
+	counter.sorted = append(counter.sorted, "")
+	copy(counter.sorted[i+1:], counter.sorted[i:])
+	counter.sorted[i] = label
+
+	for _, observer := range counter.observers {
+		observer.OnLabelCreated(label)
+	}
+
 	return
 }
 
 // getLabel returns the counter value and index for the given label.
+// Existing labels are looked up under the read lock,
+// the write lock is only taken to create a new label.
 // If the label is not found in the counter.labels map,
 // it calls newLabel to create a new counter value and index for it.
-func (counter *LabelCounter[T]) getLabel(label string, justGet bool) (c T, idx int) {
+// When the label can not be created, idx is -1.
+// update marks the label as updated now.
+func (counter *LabelCounter[T]) getLabel(label string, justGet, update bool) (c T, idx int) {
+	counter.mux.RLock()
+	if counter.closed {
+		counter.mux.RUnlock()
+		idx = -1
+		return
+	}
+
+	idx, ok := counter.labels[label]
+	if ok && idx < len(counter.value) {
+		c = counter.value[idx]
+		if update && counter.track {
+			atomic.StoreInt64(&counter.touched[idx], counter.now())
+		}
+		counter.mux.RUnlock()
+		return
+	}
+	counter.mux.RUnlock()
+
+	if justGet {
+		idx = -1
+		return
+	}
+
 	counter.mux.Lock()
 	defer counter.mux.Unlock()
 
+	return counter.createLabel(label)
+}
+
+// createLabel returns the counter value and index for the given label,
+// creating it under the label limit. The write lock must be held.
+func (counter *LabelCounter[T]) createLabel(label string) (c T, idx int) {
+	if counter.closed {
+		idx = -1
+		return
+	}
+
+	// created by others between the locks
 	idx, ok := counter.labels[label]
-	if !ok {
-		if justGet {
+	if ok && idx < len(counter.value) {
+		counter.touched[idx] = counter.now()
+		return counter.value[idx], idx
+	}
+
+	if counter.limit > 0 && len(counter.value) >= counter.limit && label != counter.overflowLabel {
+		atomic.AddUint64(&counter.dropped, 1)
+
+		switch counter.limitPolicy {
+		case LabelLimitOverflow:
+			label = counter.overflowLabel
+			idx, ok = counter.labels[label]
+			if ok {
+				counter.touched[idx] = counter.now()
+				return counter.value[idx], idx
+			}
+		case LabelLimitEvict:
+			counter.removeLabel(counter.entries[counter.oldest()])
+		default:
 			idx = -1
 			return
 		}
-		return counter.newLabel(label)
 	}
 
-	if len(counter.value) <= idx {
-		return counter.newLabel(label)
+	return counter.newLabel(label)
+}
+
+// update calls f with the counter of the label under the lock,
+// so the counter is not released by others before f returns.
+// Existing labels are updated under the read lock,
+// the label is created under the write lock if create is true.
+// It returns false if the label is not found or can not be created.
+func (counter *LabelCounter[T]) update(label string, create bool, f func(c T)) (c T, ok bool) {
+	counter.mux.RLock()
+	if !counter.closed {
+		if idx, found := counter.labels[label]; found && idx < len(counter.value) {
+			c = counter.value[idx]
+			if counter.track {
+				atomic.StoreInt64(&counter.touched[idx], counter.now())
+			}
+			f(c)
+			counter.mux.RUnlock()
+			return c, true
+		}
+	}
+	counter.mux.RUnlock()
+
+	if !create {
+		return
+	}
+
+	counter.mux.Lock()
+	defer counter.mux.Unlock()
+
+	c, idx := counter.createLabel(label)
+	if idx == -1 {
+		return
+	}
+
+	f(c)
+	return c, true
+}
+
+// oldest returns the index of the least-recently-updated label,
+// the lock must be held.
+func (counter *LabelCounter[T]) oldest() int {
+	oldest := 0
+	for idx, touched := range counter.touched {
+		if touched < counter.touched[oldest] {
+			oldest = idx
+		}
+	}
+
+	return oldest
+}
+
+// SetLabelLimit sets the maximum number of labels and the policy for new labels over it.
+// A limit not greater than 0 means no limit.
+// Existing labels over the limit are kept.
+func (counter *LabelCounter[T]) SetLabelLimit(limit int, policy LabelLimitPolicy) {
+	counter.mux.Lock()
+	defer counter.mux.Unlock()
+
+	counter.limit = limit
+	counter.limitPolicy = policy
+	counter.updateTrack()
+}
+
+// updateTrack decides whether update times are tracked, the lock must be held.
+func (counter *LabelCounter[T]) updateTrack() {
+	counter.track = counter.limitPolicy == LabelLimitEvict || counter.ttl > 0 || len(counter.labelTTL) > 0
+}
+
+// now returns the time of the clock in UnixNano, the lock must be held.
+func (counter *LabelCounter[T]) now() int64 {
+	return counter.clock().UnixNano()
+}
+
+// SetClock sets the clock used for update times and expiry, for tests.
+// It is time.Now by default.
+func (counter *LabelCounter[T]) SetClock(clock func() time.Time) {
+	counter.mux.Lock()
+	defer counter.mux.Unlock()
+
+	counter.clock = clock
+}
+
+// SetTTL sets the time after which labels not updated are expired.
+// A ttl not greater than 0 means labels never expire.
+// Labels are expired by Expire, or by the janitor started with StartJanitor.
+func (counter *LabelCounter[T]) SetTTL(ttl time.Duration) {
+	counter.mux.Lock()
+	defer counter.mux.Unlock()
+
+	counter.ttl = ttl
+	counter.updateTrack()
+}
+
+// SetLabelTTL sets the ttl of the label, overriding the ttl of SetTTL.
+// A negative ttl means the label never expires, 0 removes the override.
+// The override is removed with the label.
+func (counter *LabelCounter[T]) SetLabelTTL(label string, ttl time.Duration) {
+	counter.mux.Lock()
+	defer counter.mux.Unlock()
+
+	if ttl == 0 {
+		delete(counter.labelTTL, label)
+	} else {
+		counter.labelTTL[label] = ttl
+	}
+	counter.updateTrack()
+}
+
+// Expire removes the labels not updated within their ttl,
+// and releases their counters using the rel function.
+// It returns the number of removed labels.
+func (counter *LabelCounter[T]) Expire() int {
+	counter.mux.Lock()
+	defer counter.mux.Unlock()
+
+	now := counter.now()
+	expired := make([]string, 0)
+	for idx := range counter.value {
+		label := counter.entries[idx]
+
+		ttl, ok := counter.labelTTL[label]
+		if !ok {
+			ttl = counter.ttl
+		}
+
+		if ttl > 0 && now-counter.touched[idx] > int64(ttl) {
+			expired = append(expired, label)
+		}
+	}
+
+	for _, label := range expired {
+		counter.removeLabel(label)
 	}
 
-	return counter.value[idx], idx
+	return len(expired)
+}
+
+// StartJanitor starts a goroutine calling Expire every interval,
+// until StopJanitor is called. It does nothing if the janitor is running.
+func (counter *LabelCounter[T]) StartJanitor(interval time.Duration) {
+	counter.mux.Lock()
+	defer counter.mux.Unlock()
+
+	if counter.janitor != nil || counter.closed {
+		return
+	}
+
+	stop := make(chan struct{})
+	counter.janitor = stop
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				counter.Expire()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopJanitor stops the goroutine started by StartJanitor.
+func (counter *LabelCounter[T]) StopJanitor() {
+	counter.mux.Lock()
+	defer counter.mux.Unlock()
+
+	if counter.janitor == nil {
+		return
+	}
+
+	close(counter.janitor)
+	counter.janitor = nil
+}
+
+// SetOverflowLabel sets the label which new labels are folded into,
+// with LabelLimitOverflow. It is OverflowLabel by default.
+func (counter *LabelCounter[T]) SetOverflowLabel(label string) {
+	counter.mux.Lock()
+	defer counter.mux.Unlock()
+
+	counter.overflowLabel = label
+}
+
+// labelCreated returns the time the label is created,
+// false if the label is not found.
+func (counter *LabelCounter[T]) labelCreated(label string) (time.Time, bool) {
+	counter.mux.RLock()
+	defer counter.mux.RUnlock()
+
+	idx, ok := counter.labels[label]
+	if !ok || idx >= len(counter.created) {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, counter.created[idx]), true
+}
+
+// Dropped returns how many new labels were rejected, folded or evicted by the label limit.
+func (counter *LabelCounter[T]) Dropped() uint64 {
+	return atomic.LoadUint64(&counter.dropped)
 }
 
 // Get returns the value and the Gounter associated with the given label.
 func (counter *LabelCounter[T]) Get(label string) (v float64, c T) {
-	c, idx := counter.getLabel(label, true)
+	counter.mux.RLock()
+	defer counter.mux.RUnlock()
 
-	if idx == -1 {
-		v = 0
+	idx, ok := counter.labels[label]
+	if counter.closed || !ok || idx >= len(counter.value) {
 		return
 	}
 
+	c = counter.value[idx]
 	return c.Get(), c
 }
 
 // Set sets the value of the Gounter associated with the given label to the given value.
 func (counter *LabelCounter[T]) Set(label string, v float64) (ok bool, c T) {
-	c, _ = counter.getLabel(label, false)
-
-	ok = c.Set(v)
+	c, _ = counter.update(label, true, func(c T) {
+		ok = c.Set(v)
+	})
 	return
 }
 
-// Reset resets the counter to an empty state.
+// Reset resets the counter to an empty state,
+// and releases all counters using the rel function.
+// Counters returned before must not be used after Reset.
 func (counter *LabelCounter[T]) Reset() {
 	counter.mux.Lock()
 	defer counter.mux.Unlock()
 
+	counter.reset()
+}
+
+// reset is Reset, the lock must be held.
+func (counter *LabelCounter[T]) reset() {
+	for label := range counter.labels {
+		for _, observer := range counter.observers {
+			observer.OnLabelRemoved(label)
+		}
+	}
+
+	var zero T
+	for idx, c := range counter.value {
+		counter.rel(c)
+		counter.value[idx] = zero
+	}
+
 	counter.value = counter.value[:0]
+	counter.touched = counter.touched[:0]
+	counter.created = counter.created[:0]
+	counter.sorted = nil
+	counter.labelTTL = make(map[string]time.Duration)
+	atomic.StoreUint64(&counter.dropped, 0)
+	counter.updateTrack()
 	counter.entries = make(map[int]string)
 	counter.labels = make(map[string]int)
 }
 
-// ResetLabel resets the counter for the given label to zero.
-func (counter *LabelCounter[T]) ResetLabel(label string) {
-	c, idx := counter.getLabel(label, true)
+// isClosed say Close is called?
+func (counter *LabelCounter[T]) isClosed() bool {
+	counter.mux.RLock()
+	defer counter.mux.RUnlock()
 
-	if idx == -1 {
-		return
+	return counter.closed
+}
+
+// Close releases all counters using the rel function and stops the janitor.
+// After Close, operations on labels fail and reads return nothing.
+// It returns ErrLabelCounterClosed if it is already closed.
+func (counter *LabelCounter[T]) Close() error {
+	counter.mux.Lock()
+	defer counter.mux.Unlock()
+
+	if counter.closed {
+		return ErrLabelCounterClosed
 	}
 
-	c.Reset()
+	counter.reset()
+	counter.closed = true
+
+	if counter.janitor != nil {
+		close(counter.janitor)
+		counter.janitor = nil
+	}
+
+	return nil
+}
+
+// ResetLabel resets the counter for the given label to zero.
+func (counter *LabelCounter[T]) ResetLabel(label string) {
+	counter.update(label, false, func(c T) {
+		c.Reset()
+	})
 }
 
 // Add adds the given delta to the counter for the given label
 // and returns the updated value and a boolean indicating success or failure.
 func (counter *LabelCounter[T]) Add(label string, delta float64) (ok bool, c T) {
-	c, _ = counter.getLabel(label, false)
-	ok = c.Add(delta)
+	c, _ = counter.update(label, true, func(c T) {
+		ok = c.Add(delta)
+	})
 	return
 }
 
 // Sub subtracts the given delta from the counter for the given label
 // and returns the updated value and a boolean indicating success or failure.
 func (counter *LabelCounter[T]) Sub(label string, delta float64) (ok bool, c T) {
-	c, idx := counter.getLabel(label, true)
-	if idx == -1 {
-		return
-	}
-
-	ok = c.Sub(delta)
+	c, _ = counter.update(label, false, func(c T) {
+		ok = c.Sub(delta)
+	})
 	return
 }
 
 // Inc increments the counter for the given label by one
 // and returns the updated value and a boolean indicating success or failure.
 func (counter *LabelCounter[T]) Inc(label string) (ok bool, c T) {
-	c, _ = counter.getLabel(label, false)
-	ok = c.Inc()
+	c, _ = counter.update(label, true, func(c T) {
+		ok = c.Inc()
+	})
 	return
 }
 
 // Dec decrements the counter for the given label by one
 // and returns the updated value and a boolean indicating success or failure.
 func (counter *LabelCounter[T]) Dec(label string) (ok bool, c T) {
-	c, idx := counter.getLabel(label, true)
-	if idx == -1 {
-		return
+	c, _ = counter.update(label, false, func(c T) {
+		ok = c.Dec()
+	})
+	return
+}
+
+// Len returns the number of labels.
+func (counter *LabelCounter[T]) Len() int {
+	counter.mux.RLock()
+	defer counter.mux.RUnlock()
+
+	return len(counter.value)
+}
+
+// Labels returns all labels.
+func (counter *LabelCounter[T]) Labels() []string {
+	counter.mux.RLock()
+	defer counter.mux.RUnlock()
+
+	labels := make([]string, len(counter.value))
+	for idx := range counter.value {
+		labels[idx] = counter.entries[idx]
 	}
 
-	ok = c.Dec()
-	return
+	return labels
+}
+
+// Range calls f for each label with its value and Gounter,
+// until f returns false.
+// It holds the read lock, f must not change labels of the LabelCounter.
+func (counter *LabelCounter[T]) Range(f func(label string, v float64, c T) bool) {
+	counter.mux.RLock()
+	defer counter.mux.RUnlock()
+
+	for idx, c := range counter.value {
+		if !f(counter.entries[idx], c.Get(), c) {
+			return
+		}
+	}
+}
+
+// rangeGounter is Range with Gounter, for callers not knowing T.
+func (counter *LabelCounter[T]) rangeGounter(f func(label string, v float64, c Gounter) bool) {
+	counter.Range(func(label string, v float64, c T) bool {
+		return f(label, v, c)
+	})
+}
+
+// Snapshot returns the values of all labels.
+func (counter *LabelCounter[T]) Snapshot() map[string]float64 {
+	counter.mux.RLock()
+	defer counter.mux.RUnlock()
+
+	snapshot := make(map[string]float64, len(counter.value))
+	for idx, c := range counter.value {
+		snapshot[counter.entries[idx]] = c.Get()
+	}
+
+	return snapshot
 }
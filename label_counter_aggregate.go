@@ -0,0 +1,127 @@
+package gounter
+
+import "sort"
+
+// LabelValue is a label and the value of its counter.
+type LabelValue struct {
+	Label string
+	Value float64
+}
+
+// values returns the labels and values, taken in one pass under the read lock.
+func (counter *LabelCounter[T]) values() []LabelValue {
+	counter.mux.RLock()
+	defer counter.mux.RUnlock()
+
+	values := make([]LabelValue, len(counter.value))
+	for idx, c := range counter.value {
+		values[idx] = LabelValue{Label: counter.entries[idx], Value: c.Get()}
+	}
+
+	return values
+}
+
+// Sum returns the sum of all labels.
+func (counter *LabelCounter[T]) Sum() float64 {
+	return counter.SumWhere(func(string) bool {
+		return true
+	})
+}
+
+// SumWhere returns the sum of the labels matching the filter.
+func (counter *LabelCounter[T]) SumWhere(filter func(label string) bool) float64 {
+	counter.mux.RLock()
+	defer counter.mux.RUnlock()
+
+	var sum float64
+	for idx, c := range counter.value {
+		if filter(counter.entries[idx]) {
+			sum += c.Get()
+		}
+	}
+
+	return sum
+}
+
+// Mean returns the mean of all labels, 0 when there is no label.
+func (counter *LabelCounter[T]) Mean() float64 {
+	counter.mux.RLock()
+	defer counter.mux.RUnlock()
+
+	if len(counter.value) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, c := range counter.value {
+		sum += c.Get()
+	}
+
+	return sum / float64(len(counter.value))
+}
+
+// Min returns the label with the minimum value.
+// ok is false when there is no label.
+func (counter *LabelCounter[T]) Min() (label string, v float64, ok bool) {
+	return counter.extreme(func(a, b float64) bool {
+		return a < b
+	})
+}
+
+// Max returns the label with the maximum value.
+// ok is false when there is no label.
+func (counter *LabelCounter[T]) Max() (label string, v float64, ok bool) {
+	return counter.extreme(func(a, b float64) bool {
+		return a > b
+	})
+}
+
+// extreme returns the label whose value is better than all others.
+func (counter *LabelCounter[T]) extreme(better func(a, b float64) bool) (label string, v float64, ok bool) {
+	counter.mux.RLock()
+	defer counter.mux.RUnlock()
+
+	for idx, c := range counter.value {
+		val := c.Get()
+		if !ok || better(val, v) {
+			label, v, ok = counter.entries[idx], val, true
+		}
+	}
+
+	return
+}
+
+// TopN returns at most n labels with the largest values, sorted by value descending.
+// Labels with the same value are sorted by label.
+func (counter *LabelCounter[T]) TopN(n int) []LabelValue {
+	return sortedN(counter.values(), n, func(a, b float64) bool {
+		return a > b
+	})
+}
+
+// BottomN returns at most n labels with the smallest values, sorted by value ascending.
+// Labels with the same value are sorted by label.
+func (counter *LabelCounter[T]) BottomN(n int) []LabelValue {
+	return sortedN(counter.values(), n, func(a, b float64) bool {
+		return a < b
+	})
+}
+
+// sortedN sorts values and returns the first n.
+func sortedN(values []LabelValue, n int, less func(a, b float64) bool) []LabelValue {
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].Value != values[j].Value {
+			return less(values[i].Value, values[j].Value)
+		}
+		return values[i].Label < values[j].Label
+	})
+
+	if n < 0 {
+		n = 0
+	}
+	if n < len(values) {
+		values = values[:n]
+	}
+
+	return values
+}
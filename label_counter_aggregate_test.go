@@ -0,0 +1,71 @@
+package gounter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLabelCounter_Aggregate(t *testing.T) {
+	t.Parallel()
+
+	c := NewLabelCounterNormal()
+
+	if _, _, ok := c.Min(); ok {
+		t.Errorf("wrong result, expect %v, got %v", false, ok)
+	}
+	if v := c.Mean(); v != 0 {
+		t.Errorf("wrong result, expect %d, got %f", 0, v)
+	}
+
+	c.Set("a", 3)
+	c.Set("b", 1)
+	c.Set("c", 5)
+	c.Set("d", 3)
+	c.Set("x.e", 8)
+
+	if v := c.Sum(); v != 20 {
+		t.Errorf("wrong result, expect %d, got %f", 20, v)
+	}
+	if v := c.Mean(); v != 4 {
+		t.Errorf("wrong result, expect %d, got %f", 4, v)
+	}
+	if v := c.SumWhere(func(label string) bool { return !strings.HasPrefix(label, "x.") }); v != 12 {
+		t.Errorf("wrong result, expect %d, got %f", 12, v)
+	}
+
+	if label, v, ok := c.Min(); !ok || label != "b" || v != 1 {
+		t.Errorf("wrong result, expect %s=%d, got %s=%f", "b", 1, label, v)
+	}
+	if label, v, ok := c.Max(); !ok || label != "x.e" || v != 8 {
+		t.Errorf("wrong result, expect %s=%d, got %s=%f", "x.e", 8, label, v)
+	}
+
+	top := c.TopN(3)
+	want := []LabelValue{{"x.e", 8}, {"c", 5}, {"a", 3}}
+	if len(top) != len(want) {
+		t.Fatalf("wrong result, expect %v, got %v", want, top)
+	}
+	for i := range want {
+		if top[i] != want[i] {
+			t.Errorf("wrong result, expect %v, got %v", want, top)
+		}
+	}
+
+	bottom := c.BottomN(2)
+	want = []LabelValue{{"b", 1}, {"a", 3}}
+	if len(bottom) != len(want) {
+		t.Fatalf("wrong result, expect %v, got %v", want, bottom)
+	}
+	for i := range want {
+		if bottom[i] != want[i] {
+			t.Errorf("wrong result, expect %v, got %v", want, bottom)
+		}
+	}
+
+	if n := len(c.TopN(10)); n != 5 {
+		t.Errorf("wrong result, expect %d, got %d", 5, n)
+	}
+	if n := len(c.TopN(-1)); n != 0 {
+		t.Errorf("wrong result, expect %d, got %d", 0, n)
+	}
+}
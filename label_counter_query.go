@@ -0,0 +1,136 @@
+package gounter
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// Prefix returns the labels starting with the prefix and their values, sorted by label.
+// It uses the sorted index, not scanning every label.
+// The prefix is matched as a raw string, so "svc.api" also matches "svc.apiv2",
+// pass the trailing separator ("svc.api.") or use Subtree.
+func (counter *LabelCounter[T]) Prefix(prefix string) []LabelValue {
+	counter.mux.RLock()
+	defer counter.mux.RUnlock()
+
+	values := make([]LabelValue, 0)
+	counter.rangePrefix(prefix, func(label string, c T) {
+		values = append(values, LabelValue{Label: label, Value: c.Get()})
+	})
+
+	return values
+}
+
+// SumPrefix returns the sum of the labels starting with the prefix.
+// Like Prefix, the prefix is matched as a raw string.
+func (counter *LabelCounter[T]) SumPrefix(prefix string) float64 {
+	counter.mux.RLock()
+	defer counter.mux.RUnlock()
+
+	var sum float64
+	counter.rangePrefix(prefix, func(label string, c T) {
+		sum += c.Get()
+	})
+
+	return sum
+}
+
+// Subtree returns the label equal to the prefix and the labels under it,
+// split by the separator, and their values, sorted by label.
+// Subtree("svc.api", ".") matches "svc.api" and "svc.api.get", not "svc.apiv2".
+// An empty prefix matches every label.
+func (counter *LabelCounter[T]) Subtree(prefix, sep string) []LabelValue {
+	counter.mux.RLock()
+	defer counter.mux.RUnlock()
+
+	values := make([]LabelValue, 0)
+	counter.rangeSubtree(prefix, sep, func(label string, c T) {
+		values = append(values, LabelValue{Label: label, Value: c.Get()})
+	})
+
+	return values
+}
+
+// SumSubtree returns the sum of the labels matched by Subtree.
+func (counter *LabelCounter[T]) SumSubtree(prefix, sep string) float64 {
+	counter.mux.RLock()
+	defer counter.mux.RUnlock()
+
+	var sum float64
+	counter.rangeSubtree(prefix, sep, func(label string, c T) {
+		sum += c.Get()
+	})
+
+	return sum
+}
+
+// Glob returns the labels matching the pattern and their values, sorted by label.
+// The pattern syntax is the same as path.Match,
+// so '*' does not match '/'.
+// The literal prefix of the pattern narrows the labels to scan.
+func (counter *LabelCounter[T]) Glob(pattern string) ([]LabelValue, error) {
+	// check the pattern even if no label
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	counter.mux.RLock()
+	defer counter.mux.RUnlock()
+
+	values := make([]LabelValue, 0)
+	counter.rangePrefix(globPrefix(pattern), func(label string, c T) {
+		if ok, _ := path.Match(pattern, label); ok {
+			values = append(values, LabelValue{Label: label, Value: c.Get()})
+		}
+	})
+
+	return values, nil
+}
+
+// SumGlob returns the sum of the labels matching the pattern.
+func (counter *LabelCounter[T]) SumGlob(pattern string) (float64, error) {
+	values, err := counter.Glob(pattern)
+	if err != nil {
+		return 0, err
+	}
+
+	var sum float64
+	for _, value := range values {
+		sum += value.Value
+	}
+
+	return sum, nil
+}
+
+// rangePrefix calls f for each label starting with the prefix in order,
+// the read lock must be held.
+func (counter *LabelCounter[T]) rangePrefix(prefix string, f func(label string, c T)) {
+	for i := sort.SearchStrings(counter.sorted, prefix); i < len(counter.sorted); i++ {
+		label := counter.sorted[i]
+		if !strings.HasPrefix(label, prefix) {
+			return
+		}
+
+		f(label, counter.value[counter.labels[label]])
+	}
+}
+
+// rangeSubtree calls f for each label matched by Subtree in order,
+// the read lock must be held.
+func (counter *LabelCounter[T]) rangeSubtree(prefix, sep string, f func(label string, c T)) {
+	counter.rangePrefix(prefix, func(label string, c T) {
+		if prefix == "" || len(label) == len(prefix) || strings.HasPrefix(label[len(prefix):], sep) {
+			f(label, c)
+		}
+	})
+}
+
+// globPrefix returns the literal prefix of the pattern.
+func globPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		return pattern[:i]
+	}
+
+	return pattern
+}
@@ -0,0 +1,127 @@
+package gounter
+
+import (
+	"path"
+	"testing"
+)
+
+func testQueryLabelCounter() *LabelCounter[*Counter] {
+	c := NewLabelCounterNormal()
+
+	c.Set("svc.api.users.get", 1)
+	c.Set("svc.api.users.post", 2)
+	c.Set("svc.api.orders.get", 4)
+	c.Set("svc.apix", 8)
+	c.Set("svc.web.index", 16)
+	c.Set("other", 32)
+
+	return c
+}
+
+func TestLabelCounter_Prefix(t *testing.T) {
+	t.Parallel()
+
+	c := testQueryLabelCounter()
+
+	values := c.Prefix("svc.api.")
+	want := []LabelValue{
+		{"svc.api.orders.get", 4},
+		{"svc.api.users.get", 1},
+		{"svc.api.users.post", 2},
+	}
+	if len(values) != len(want) {
+		t.Fatalf("wrong result, expect %v, got %v", want, values)
+	}
+	for i := range want {
+		if values[i] != want[i] {
+			t.Errorf("wrong result, expect %v, got %v", want, values)
+		}
+	}
+
+	if v := c.SumPrefix("svc.api"); v != 15 {
+		t.Errorf("wrong result, expect %d, got %f", 15, v)
+	}
+	if v := c.SumPrefix(""); v != 63 {
+		t.Errorf("wrong result, expect %d, got %f", 63, v)
+	}
+
+	// the index follows removes
+	c.RemoveLabel("svc.api.users.get")
+	c.RemoveLabel("other")
+	if v := c.SumPrefix("svc.api."); v != 6 {
+		t.Errorf("wrong result, expect %d, got %f", 6, v)
+	}
+	if v := c.SumPrefix(""); v != 30 {
+		t.Errorf("wrong result, expect %d, got %f", 30, v)
+	}
+
+	c.Reset()
+	if n := len(c.Prefix("")); n != 0 {
+		t.Errorf("wrong result, expect %d, got %d", 0, n)
+	}
+}
+
+func TestLabelCounter_Subtree(t *testing.T) {
+	t.Parallel()
+
+	c := testQueryLabelCounter()
+	c.Set("svc.api", 64)
+
+	values := c.Subtree("svc.api", ".")
+	want := []LabelValue{
+		{"svc.api", 64},
+		{"svc.api.orders.get", 4},
+		{"svc.api.users.get", 1},
+		{"svc.api.users.post", 2},
+	}
+	if len(values) != len(want) {
+		t.Fatalf("wrong result, expect %v, got %v", want, values)
+	}
+	for i := range want {
+		if values[i] != want[i] {
+			t.Errorf("wrong result, expect %v, got %v", want, values)
+		}
+	}
+
+	// "svc.apix" is not under "svc.api"
+	if v := c.SumSubtree("svc.api", "."); v != 71 {
+		t.Errorf("wrong result, expect %d, got %f", 71, v)
+	}
+	if v := c.SumPrefix("svc.api"); v != 79 {
+		t.Errorf("wrong result, expect %d, got %f", 79, v)
+	}
+	if v := c.SumSubtree("svc.api.users", "."); v != 3 {
+		t.Errorf("wrong result, expect %d, got %f", 3, v)
+	}
+	if v := c.SumSubtree("", "."); v != 127 {
+		t.Errorf("wrong result, expect %d, got %f", 127, v)
+	}
+}
+
+func TestLabelCounter_Glob(t *testing.T) {
+	t.Parallel()
+
+	c := testQueryLabelCounter()
+
+	values, err := c.Glob("svc.api.*.get")
+	if err != nil {
+		t.Fatalf("wrong result, expect %v, got %v", nil, err)
+	}
+	if len(values) != 2 || values[0].Label != "svc.api.orders.get" || values[1].Label != "svc.api.users.get" {
+		t.Errorf("wrong result, got %v", values)
+	}
+
+	if v, err := c.SumGlob("svc.*"); err != nil || v != 31 {
+		t.Errorf("wrong result, expect %d, got %f, %v", 31, v, err)
+	}
+	if v, err := c.SumGlob("?ther"); err != nil || v != 32 {
+		t.Errorf("wrong result, expect %d, got %f, %v", 32, v, err)
+	}
+
+	if _, err := c.Glob("svc.["); err != path.ErrBadPattern {
+		t.Errorf("wrong result, expect %v, got %v", path.ErrBadPattern, err)
+	}
+	if _, err := c.SumGlob("["); err != path.ErrBadPattern {
+		t.Errorf("wrong result, expect %v, got %v", path.ErrBadPattern, err)
+	}
+}
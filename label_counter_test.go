@@ -1,8 +1,12 @@
 package gounter
 
 import (
+	"sort"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func testGenerateLabels() []string {
@@ -120,6 +124,259 @@ func TestLabelCounter_Reset(t *testing.T) {
 	}
 }
 
+func TestLabelCounter_Iterate(t *testing.T) {
+	t.Parallel()
+
+	c := NewLabelCounterNormal()
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	c.RemoveLabel("a")
+
+	if n := c.Len(); n != 2 {
+		t.Errorf("wrong result, expect %d, got %d", 2, n)
+	}
+
+	labels := c.Labels()
+	if len(labels) != 2 || labels[0] != "c" || labels[1] != "b" {
+		t.Errorf("wrong result, expect %v, got %v", []string{"c", "b"}, labels)
+	}
+
+	snapshot := c.Snapshot()
+	if len(snapshot) != 2 || snapshot["b"] != 2 || snapshot["c"] != 3 {
+		t.Errorf("wrong result, expect %v, got %v", map[string]float64{"b": 2, "c": 3}, snapshot)
+	}
+
+	n := 0
+	c.Range(func(label string, v float64, cc *Counter) bool {
+		if v != cc.Get() || v != snapshot[label] {
+			t.Errorf("label %s, wrong result, expect %f, got %f", label, snapshot[label], v)
+		}
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Errorf("wrong result, expect %d, got %d", 1, n)
+	}
+}
+
+func TestLabelCounter_LimitReject(t *testing.T) {
+	t.Parallel()
+
+	c := NewLabelCounterNormal()
+	c.SetLabelLimit(2, LabelLimitReject)
+
+	c.Inc("a")
+	c.Inc("b")
+	if ok, cc := c.Inc("c"); ok || cc != nil {
+		t.Errorf("wrong result, expect %v, got %v", false, ok)
+	}
+	if ok, _ := c.Inc("a"); !ok {
+		t.Errorf("wrong result, expect %v, got %v", true, ok)
+	}
+
+	if n := c.Len(); n != 2 {
+		t.Errorf("wrong result, expect %d, got %d", 2, n)
+	}
+	if n := c.Dropped(); n != 1 {
+		t.Errorf("wrong result, expect %d, got %d", 1, n)
+	}
+}
+
+func TestLabelCounter_LimitOverflow(t *testing.T) {
+	t.Parallel()
+
+	c := NewLabelCounterNormal()
+	c.SetLabelLimit(2, LabelLimitOverflow)
+
+	c.Inc("a")
+	c.Inc("b")
+	c.Add("c", 2)
+	c.Add("d", 3)
+
+	if v, _ := c.Get(OverflowLabel); v != 5 {
+		t.Errorf("wrong result, expect %d, got %f", 5, v)
+	}
+	if n := c.Len(); n != 3 {
+		t.Errorf("wrong result, expect %d, got %d", 3, n)
+	}
+	if n := c.Dropped(); n != 2 {
+		t.Errorf("wrong result, expect %d, got %d", 2, n)
+	}
+
+	c.Reset()
+	c.SetOverflowLabel("other")
+	c.Inc("a")
+	c.Inc("b")
+	c.Inc("c")
+	if v, _ := c.Get("other"); v != 1 {
+		t.Errorf("wrong result, expect %d, got %f", 1, v)
+	}
+}
+
+func TestLabelCounter_LimitEvict(t *testing.T) {
+	t.Parallel()
+
+	c := NewLabelCounterNormal()
+	c.SetLabelLimit(2, LabelLimitEvict)
+
+	c.Inc("a")
+	time.Sleep(time.Millisecond)
+	c.Inc("b")
+	time.Sleep(time.Millisecond)
+	// a is updated after b
+	c.Inc("a")
+	time.Sleep(time.Millisecond)
+	// reading does not update
+	c.Get("b")
+	c.Inc("c")
+
+	labels := c.Labels()
+	sort.Strings(labels)
+	if len(labels) != 2 || labels[0] != "a" || labels[1] != "c" {
+		t.Errorf("wrong result, expect %v, got %v", []string{"a", "c"}, labels)
+	}
+	if v, _ := c.Get("a"); v != 2 {
+		t.Errorf("wrong result, expect %d, got %f", 2, v)
+	}
+	if n := c.Dropped(); n != 1 {
+		t.Errorf("wrong result, expect %d, got %d", 1, n)
+	}
+}
+
+func TestLabelCounter_ResetRelease(t *testing.T) {
+	t.Parallel()
+
+	var acquired, released int
+	acq := func() *Counter {
+		acquired++
+		return AcquireCounter()
+	}
+	rel := func(c *Counter) {
+		released++
+		ReleaseCounter(c)
+	}
+
+	c := NewLabelCounter[*Counter](acq, rel)
+	c.Inc("a")
+	c.Inc("b")
+	c.Reset()
+
+	if acquired != 2 || released != 2 {
+		t.Errorf("wrong result, expect %d released, got %d of %d", 2, released, acquired)
+	}
+
+	c.Inc("a")
+	if err := c.Close(); err != nil {
+		t.Errorf("wrong result, expect %v, got %v", nil, err)
+	}
+	if released != 3 {
+		t.Errorf("wrong result, expect %d, got %d", 3, released)
+	}
+
+	if ok, _ := c.Inc("a"); ok {
+		t.Errorf("wrong result, expect %v, got %v", false, ok)
+	}
+	if v, cc := c.Get("a"); v != 0 || cc != nil {
+		t.Errorf("wrong result, expect %d, got %f", 0, v)
+	}
+	if n := c.Len(); n != 0 {
+		t.Errorf("wrong result, expect %d, got %d", 0, n)
+	}
+	if acquired != 3 {
+		t.Errorf("wrong result, expect %d, got %d", 3, acquired)
+	}
+
+	if err := c.Close(); err != ErrLabelCounterClosed {
+		t.Errorf("wrong result, expect %v, got %v", ErrLabelCounterClosed, err)
+	}
+}
+
+// testClock is a clock for tests.
+type testClock struct {
+	mux sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.now
+}
+
+func (c *testClock) Add(d time.Duration) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestLabelCounter_Expire(t *testing.T) {
+	t.Parallel()
+
+	clock := &testClock{now: time.Unix(0, 0)}
+
+	c := NewLabelCounterNormal()
+	c.SetClock(clock.Now)
+	c.SetTTL(time.Minute)
+
+	c.Inc("a")
+	c.Inc("b")
+	c.Inc("forever")
+	c.SetLabelTTL("forever", -1)
+	c.Inc("short")
+	c.SetLabelTTL("short", time.Second)
+
+	clock.Add(2 * time.Second)
+	if n := c.Expire(); n != 1 {
+		t.Errorf("wrong result, expect %d, got %d", 1, n)
+	}
+
+	clock.Add(30 * time.Second)
+	c.Inc("a")
+	clock.Add(40 * time.Second)
+	if n := c.Expire(); n != 1 {
+		t.Errorf("wrong result, expect %d, got %d", 1, n)
+	}
+
+	labels := c.Labels()
+	sort.Strings(labels)
+	if len(labels) != 2 || labels[0] != "a" || labels[1] != "forever" {
+		t.Errorf("wrong result, expect %v, got %v", []string{"a", "forever"}, labels)
+	}
+
+	// the override is removed with the label
+	c.RemoveLabel("forever")
+	c.Inc("forever")
+	clock.Add(2 * time.Minute)
+	if n := c.Expire(); n != 2 {
+		t.Errorf("wrong result, expect %d, got %d", 2, n)
+	}
+}
+
+func TestLabelCounter_Janitor(t *testing.T) {
+	t.Parallel()
+
+	c := NewLabelCounterNormal()
+	c.SetTTL(time.Millisecond)
+	c.StartJanitor(time.Millisecond)
+	c.StartJanitor(time.Millisecond)
+	defer c.StopJanitor()
+
+	c.Inc("a")
+
+	deadline := time.Now().Add(time.Second)
+	for c.Len() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	c.StopJanitor()
+	c.StopJanitor()
+}
+
 // testLabelCounterIncDec tests LabelCounter.
 func testLabelCounterIncDec[T Gounter](labels []string, count int, c *LabelCounter[T], t *testing.T) {
 	wg := sync.WaitGroup{}
@@ -166,3 +423,108 @@ func testLabelCounterIncDec[T Gounter](labels []string, count int, c *LabelCount
 		}
 	}
 }
+
+func BenchmarkLabelCounterInc(b *testing.B) {
+	labels := testGenerateLabels()
+
+	c := NewLabelCounterNormal()
+	for _, label := range labels {
+		c.Inc(label)
+	}
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.Inc(labels[i%len(labels)])
+			i++
+		}
+	})
+}
+
+func BenchmarkLabelCounterGet(b *testing.B) {
+	labels := testGenerateLabels()
+
+	c := NewLabelCounterNormal()
+	for _, label := range labels {
+		c.Inc(label)
+	}
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.Get(labels[i%len(labels)])
+			i++
+		}
+	})
+}
+
+func TestLabelCounterEvictConcurrent(t *testing.T) {
+	t.Parallel()
+
+	c := NewLabelCounterWithMax(1e9)
+	c.SetLabelLimit(4, LabelLimitEvict)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 2000; j++ {
+				c.Inc(strconv.Itoa((i + j) % 16))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := c.Len(); n > 4 {
+		t.Fatalf("should be at most 4, but %d", n)
+	}
+}
+
+func TestLabelCounterJanitorConcurrent(t *testing.T) {
+	t.Parallel()
+
+	var released uint64
+	c := NewLabelCounter[*MaxCounter](func() *MaxCounter {
+		return AcquireMaxCounter(1e9)
+	}, func(m *MaxCounter) {
+		atomic.AddUint64(&released, 1)
+		ReleaseMaxCounter(m)
+	})
+	removed := &removeObserver{}
+	c.Observe(removed)
+	c.SetTTL(time.Nanosecond)
+	c.StartJanitor(time.Microsecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 2000; j++ {
+				c.Inc(strconv.Itoa((i + j) % 16))
+				if j%100 == 0 {
+					c.Expire()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	c.StopJanitor()
+
+	if n := atomic.LoadUint64(&removed.n); n == 0 || n != atomic.LoadUint64(&released) {
+		t.Fatalf("should release %d, but %d", n, atomic.LoadUint64(&released))
+	}
+}
+
+// removeObserver counts removed labels.
+type removeObserver struct {
+	NopObserver
+	n uint64
+}
+
+func (o *removeObserver) OnLabelRemoved(label string) {
+	atomic.AddUint64(&o.n, 1)
+}
@@ -0,0 +1,1197 @@
+package gounter
+
+import (
+	"encoding"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"sync/atomic"
+)
+
+// marshalVersion is the version of the binary encoding.
+const marshalVersion byte = 1
+
+// kinds of the binary encoding, written after the version.
+const (
+	kindCounter        byte = 'c'
+	kindMaxCounter     byte = 'm'
+	kindLabelCounter   byte = 'l'
+	kindInt64Counter   byte = 'i'
+	kindShardedCounter byte = 's'
+	kindPreciseCounter byte = 'p'
+	kindTypedCounter   byte = 't'
+)
+
+// appendFloat appends the float64 in little endian.
+func appendFloat(b []byte, v float64) []byte {
+	return appendUint64(b, math.Float64bits(v))
+}
+
+// appendUint64 appends the uint64 in little endian.
+func appendUint64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+
+	return append(b, buf[:]...)
+}
+
+// appendUvarint appends the uvarint.
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+
+	return append(b, buf[:n]...)
+}
+
+// appendBytes appends the bytes with the uvarint length.
+func appendBytes(b, v []byte) []byte {
+	b = appendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// decoder reads the binary encoding,
+// the first error is kept and later reads return zero values.
+type decoder struct {
+	data []byte
+	err  error
+}
+
+// newDecoder checks the version and the kind, and returns a decoder of the payload.
+func newDecoder(data []byte, kind byte) *decoder {
+	d := &decoder{}
+	switch {
+	case len(data) < 2:
+		d.err = ErrInvalidEncoding
+	case data[0] != marshalVersion:
+		d.err = ErrUnsupportedVersion
+	case data[1] != kind:
+		d.err = ErrInvalidEncoding
+	default:
+		d.data = data[2:]
+	}
+
+	return d
+}
+
+// float reads a float64.
+func (d *decoder) float() float64 {
+	return math.Float64frombits(d.uint64())
+}
+
+// uint64 reads a uint64 in little endian.
+func (d *decoder) uint64() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.data) < 8 {
+		d.err = ErrInvalidEncoding
+		return 0
+	}
+
+	v := binary.LittleEndian.Uint64(d.data)
+	d.data = d.data[8:]
+
+	return v
+}
+
+// uint8 reads a byte.
+func (d *decoder) uint8() byte {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.data) < 1 {
+		d.err = ErrInvalidEncoding
+		return 0
+	}
+
+	v := d.data[0]
+	d.data = d.data[1:]
+
+	return v
+}
+
+// uvarint reads a uvarint.
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.err = ErrInvalidEncoding
+		return 0
+	}
+	d.data = d.data[n:]
+
+	return v
+}
+
+// bytes reads bytes with the uvarint length.
+func (d *decoder) bytes() []byte {
+	n := d.uvarint()
+	if d.err != nil {
+		return nil
+	}
+	if uint64(len(d.data)) < n {
+		d.err = ErrInvalidEncoding
+		return nil
+	}
+
+	v := d.data[:n]
+	d.data = d.data[n:]
+
+	return v
+}
+
+// done returns the error, or ErrInvalidEncoding if there is data left.
+func (d *decoder) done() error {
+	if d.err == nil && len(d.data) != 0 {
+		return ErrInvalidEncoding
+	}
+
+	return d.err
+}
+
+// decoded is the state decoded by decodeBinary, not applied yet.
+type decoded struct {
+	// apply sets the decoded state.
+	apply func() error
+	// discard releases the decoded state if it is not applied, it may be nil.
+	discard func()
+}
+
+// discardAll discards the decoded states.
+func discardAll(decodes []decoded) {
+	for _, dec := range decodes {
+		if dec.discard != nil {
+			dec.discard()
+		}
+	}
+}
+
+// binaryDecoder is implemented by the counters of gounter,
+// decodeBinary decodes data without changing the counter.
+type binaryDecoder interface {
+	decodeBinary(data []byte) (decoded, error)
+}
+
+// jsonFloat is a float64 in JSON,
+// NaN and infinities are written as the strings "NaN", "+Inf" and "-Inf".
+type jsonFloat float64
+
+// MarshalJSON implements json.Marshaler.
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	v := float64(f)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return []byte(`"` + formatFloat(v) + `"`), nil
+	}
+
+	return strconv.AppendFloat(nil, v, 'g', -1, 64), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (f *jsonFloat) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+
+		switch s {
+		case "NaN":
+			*f = jsonFloat(math.NaN())
+		case "+Inf":
+			*f = jsonFloat(math.Inf(1))
+		case "-Inf":
+			*f = jsonFloat(math.Inf(-1))
+		default:
+			return ErrInvalidEncoding
+		}
+		return nil
+	}
+
+	var v float64
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*f = jsonFloat(v)
+
+	return nil
+}
+
+// counterJSON is the JSON of Counter.
+type counterJSON struct {
+	Value jsonFloat `json:"value"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (c *Counter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(counterJSON{Value: jsonFloat(c.Real())})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *Counter) UnmarshalJSON(data []byte) error {
+	var v counterJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	c.Set(float64(v.Value))
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (c *Counter) MarshalBinary() ([]byte, error) {
+	b := []byte{marshalVersion, kindCounter}
+
+	return appendFloat(b, c.Real()), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (c *Counter) UnmarshalBinary(data []byte) error {
+	dec, err := c.decodeBinary(data)
+	if err != nil {
+		return err
+	}
+
+	return dec.apply()
+}
+
+// decodeBinary decodes data without changing the Counter,
+// apply sets the decoded value.
+func (c *Counter) decodeBinary(data []byte) (decoded, error) {
+	d := newDecoder(data, kindCounter)
+	v := d.float()
+	if err := d.done(); err != nil {
+		return decoded{}, err
+	}
+
+	return decoded{apply: func() error {
+		c.Set(v)
+		return nil
+	}}, nil
+}
+
+// maxCounterJSON is the JSON of MaxCounter.
+type maxCounterJSON struct {
+	Value   jsonFloat      `json:"value"`
+	Max     jsonFloat      `json:"max"`
+	Min     jsonFloat      `json:"min"`
+	Done    bool           `json:"done"`
+	MinDone bool           `json:"min_done"`
+	Policy  OverflowPolicy `json:"policy"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (c *MaxCounter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(maxCounterJSON{
+		Value:   jsonFloat(c.Real()),
+		Max:     jsonFloat(c.GetMax()),
+		Min:     jsonFloat(c.GetMin()),
+		Done:    c.isDone(),
+		MinDone: c.isMinDone(),
+		Policy:  c.GetPolicy(),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *MaxCounter) UnmarshalJSON(data []byte) error {
+	var v maxCounterJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	if v.Policy > OverflowAllow {
+		return ErrInvalidEncoding
+	}
+
+	c.restore(float64(v.Value), float64(v.Max), float64(v.Min), v.Done, v.MinDone, v.Policy)
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (c *MaxCounter) MarshalBinary() ([]byte, error) {
+	b := []byte{marshalVersion, kindMaxCounter}
+	b = appendFloat(b, c.Real())
+	b = appendFloat(b, c.GetMax())
+	b = appendFloat(b, c.GetMin())
+	b = appendUvarint(b, uint64(atomic.LoadUint32(&c.done)))
+	b = appendUvarint(b, uint64(c.GetPolicy()))
+
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (c *MaxCounter) UnmarshalBinary(data []byte) error {
+	dec, err := c.decodeBinary(data)
+	if err != nil {
+		return err
+	}
+
+	return dec.apply()
+}
+
+// decodeBinary decodes data without changing the MaxCounter,
+// apply sets the decoded state.
+func (c *MaxCounter) decodeBinary(data []byte) (decoded, error) {
+	d := newDecoder(data, kindMaxCounter)
+	value, max, min := d.float(), d.float(), d.float()
+	done, policy := d.uvarint(), d.uvarint()
+	if err := d.done(); err != nil {
+		return decoded{}, err
+	}
+
+	if done&^uint64(doneMax|doneMin) != 0 || policy > uint64(OverflowAllow) {
+		return decoded{}, ErrInvalidEncoding
+	}
+
+	return decoded{apply: func() error {
+		c.restore(value, max, min, done&uint64(doneMax) != 0, done&uint64(doneMin) != 0, OverflowPolicy(policy))
+		return nil
+	}}, nil
+}
+
+// restore sets the whole state of the MaxCounter.
+func (c *MaxCounter) restore(value, max, min float64, done, minDone bool, policy OverflowPolicy) {
+	if c.counter == nil {
+		c.counter = AcquireCounter()
+	}
+
+	c.SetPolicy(policy)
+	c.SetMin(min)
+	c.setFlag(doneMax, done)
+	c.setFlag(doneMin, minDone)
+	c.counter.Set(value)
+	c.SetMax(max)
+}
+
+// int64CounterJSON is the JSON of Int64Counter.
+type int64CounterJSON struct {
+	Value int64 `json:"value"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (c *Int64Counter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int64CounterJSON{Value: c.RealInt()})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *Int64Counter) UnmarshalJSON(data []byte) error {
+	var v int64CounterJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	c.SetInt(v.Value)
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (c *Int64Counter) MarshalBinary() ([]byte, error) {
+	b := []byte{marshalVersion, kindInt64Counter}
+
+	return appendUint64(b, uint64(c.RealInt())), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (c *Int64Counter) UnmarshalBinary(data []byte) error {
+	dec, err := c.decodeBinary(data)
+	if err != nil {
+		return err
+	}
+
+	return dec.apply()
+}
+
+// decodeBinary decodes data without changing the Int64Counter,
+// apply sets the decoded value.
+func (c *Int64Counter) decodeBinary(data []byte) (decoded, error) {
+	d := newDecoder(data, kindInt64Counter)
+	v := int64(d.uint64())
+	if err := d.done(); err != nil {
+		return decoded{}, err
+	}
+
+	return decoded{apply: func() error {
+		c.SetInt(v)
+		return nil
+	}}, nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (c *ShardedCounter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(counterJSON{Value: jsonFloat(c.Real())})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *ShardedCounter) UnmarshalJSON(data []byte) error {
+	var v counterJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	c.Set(float64(v.Value))
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (c *ShardedCounter) MarshalBinary() ([]byte, error) {
+	b := []byte{marshalVersion, kindShardedCounter}
+
+	return appendFloat(b, c.Real()), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (c *ShardedCounter) UnmarshalBinary(data []byte) error {
+	dec, err := c.decodeBinary(data)
+	if err != nil {
+		return err
+	}
+
+	return dec.apply()
+}
+
+// decodeBinary decodes data without changing the ShardedCounter,
+// apply sets the decoded value.
+func (c *ShardedCounter) decodeBinary(data []byte) (decoded, error) {
+	d := newDecoder(data, kindShardedCounter)
+	v := d.float()
+	if err := d.done(); err != nil {
+		return decoded{}, err
+	}
+
+	return decoded{apply: func() error {
+		c.Set(v)
+		return nil
+	}}, nil
+}
+
+// MarshalJSON implements json.Marshaler.
+// The compensation is folded into the value.
+func (c *PreciseCounter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(counterJSON{Value: jsonFloat(c.Real())})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *PreciseCounter) UnmarshalJSON(data []byte) error {
+	var v counterJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	c.Set(float64(v.Value))
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+// The sum and the compensation are written.
+func (c *PreciseCounter) MarshalBinary() ([]byte, error) {
+	c.mux.Lock()
+	sum, comp := c.sum, c.comp
+	c.mux.Unlock()
+
+	b := []byte{marshalVersion, kindPreciseCounter}
+	b = appendFloat(b, sum)
+
+	return appendFloat(b, comp), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (c *PreciseCounter) UnmarshalBinary(data []byte) error {
+	dec, err := c.decodeBinary(data)
+	if err != nil {
+		return err
+	}
+
+	return dec.apply()
+}
+
+// decodeBinary decodes data without changing the PreciseCounter,
+// apply sets the decoded sum and compensation.
+func (c *PreciseCounter) decodeBinary(data []byte) (decoded, error) {
+	d := newDecoder(data, kindPreciseCounter)
+	sum, comp := d.float(), d.float()
+	if err := d.done(); err != nil {
+		return decoded{}, err
+	}
+
+	return decoded{apply: func() error {
+		c.mux.Lock()
+		c.sum, c.comp = sum, comp
+		c.mux.Unlock()
+
+		return nil
+	}}, nil
+}
+
+// typedTag returns the tag of N in the binary encoding of TypedCounter.
+func typedTag[N Number]() byte {
+	var n N
+	switch any(n).(type) {
+	case int32:
+		return '4'
+	case int64:
+		return '8'
+	case uint64:
+		return 'u'
+	}
+
+	return 'f'
+}
+
+// typedJSON is the JSON of TypedCounter.
+type typedJSON struct {
+	Value json.RawMessage `json:"value"`
+}
+
+// MarshalJSON implements json.Marshaler.
+// Integers are written exactly, float64 as Counter does.
+func (c *TypedCounter[N]) MarshalJSON() ([]byte, error) {
+	if f, ok := any(c.Real()).(float64); ok {
+		return json.Marshal(counterJSON{Value: jsonFloat(f)})
+	}
+
+	value, err := json.Marshal(c.Real())
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(typedJSON{Value: value})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *TypedCounter[N]) UnmarshalJSON(data []byte) error {
+	var v typedJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	var n N
+	if _, ok := any(n).(float64); ok {
+		var f jsonFloat
+		if err := f.UnmarshalJSON(v.Value); err != nil {
+			return err
+		}
+		c.Set(N(f))
+		return nil
+	}
+
+	if err := json.Unmarshal(v.Value, &n); err != nil {
+		return err
+	}
+
+	c.Set(n)
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+// The type of N is written, it must be the same to unmarshal.
+func (c *TypedCounter[N]) MarshalBinary() ([]byte, error) {
+	b := []byte{marshalVersion, kindTypedCounter, typedTag[N]()}
+
+	return appendUint64(b, atomic.LoadUint64(&c.bits)), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (c *TypedCounter[N]) UnmarshalBinary(data []byte) error {
+	dec, err := c.decodeBinary(data)
+	if err != nil {
+		return err
+	}
+
+	return dec.apply()
+}
+
+// decodeBinary decodes data without changing the TypedCounter,
+// apply sets the decoded value.
+func (c *TypedCounter[N]) decodeBinary(data []byte) (decoded, error) {
+	d := newDecoder(data, kindTypedCounter)
+	tag, bits := d.uint8(), d.uint64()
+	if err := d.done(); err != nil {
+		return decoded{}, err
+	}
+
+	if tag != typedTag[N]() {
+		return decoded{}, ErrInvalidEncoding
+	}
+
+	return decoded{apply: func() error {
+		atomic.StoreUint64(&c.bits, bits)
+		return nil
+	}}, nil
+}
+
+// MarshalJSON implements json.Marshaler,
+// ErrUnsupportedCounter is returned if the TypedGounter does not implement it.
+func (a *GounterAdapter[N]) MarshalJSON() ([]byte, error) {
+	m, ok := a.counter.(json.Marshaler)
+	if !ok {
+		return nil, ErrUnsupportedCounter
+	}
+
+	return m.MarshalJSON()
+}
+
+// UnmarshalJSON implements json.Unmarshaler,
+// ErrUnsupportedCounter is returned if the TypedGounter does not implement it.
+func (a *GounterAdapter[N]) UnmarshalJSON(data []byte) error {
+	u, ok := a.counter.(json.Unmarshaler)
+	if !ok {
+		return ErrUnsupportedCounter
+	}
+
+	return u.UnmarshalJSON(data)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler,
+// ErrUnsupportedCounter is returned if the TypedGounter does not implement it.
+func (a *GounterAdapter[N]) MarshalBinary() ([]byte, error) {
+	m, ok := a.counter.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, ErrUnsupportedCounter
+	}
+
+	return m.MarshalBinary()
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler,
+// ErrUnsupportedCounter is returned if the TypedGounter does not implement it.
+func (a *GounterAdapter[N]) UnmarshalBinary(data []byte) error {
+	dec, err := a.decodeBinary(data)
+	if err != nil {
+		return err
+	}
+
+	return dec.apply()
+}
+
+// decodeBinary decodes data without changing the TypedGounter,
+// apply sets the decoded state.
+func (a *GounterAdapter[N]) decodeBinary(data []byte) (decoded, error) {
+	switch u := a.counter.(type) {
+	case binaryDecoder:
+		return u.decodeBinary(data)
+	case encoding.BinaryUnmarshaler:
+		// not checked before apply
+		return decoded{apply: func() error {
+			return u.UnmarshalBinary(data)
+		}}, nil
+	}
+
+	return decoded{}, ErrUnsupportedCounter
+}
+
+// labelCounterJSON is the JSON of LabelCounter.
+type labelCounterJSON struct {
+	Labels map[string]json.RawMessage `json:"labels"`
+}
+
+// sortedRange calls f for each label sorted.
+func (counter *LabelCounter[T]) sortedRange(f func(label string, c T) error) error {
+	counter.mux.RLock()
+	defer counter.mux.RUnlock()
+
+	for _, label := range counter.sorted {
+		if err := f(label, counter.value[counter.labels[label]]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restore replaces all labels of the LabelCounter,
+// set is called with a new counter of each label.
+// All counters are decoded before the labels are replaced,
+// so nothing is changed on error.
+func (counter *LabelCounter[T]) restore(labels []string, set func(i int, c T) error) error {
+	dec, err := counter.decodeLabels(labels, set)
+	if err != nil {
+		return err
+	}
+
+	return dec.apply()
+}
+
+// decodeLabels decodes the counters of all labels without changing the LabelCounter,
+// apply replaces the labels and discard releases the decoded counters.
+func (counter *LabelCounter[T]) decodeLabels(labels []string, set func(i int, c T) error) (decoded, error) {
+	counter.mux.RLock()
+	closed, over := counter.closed, counter.overLimit(labels)
+	counter.mux.RUnlock()
+
+	if closed {
+		return decoded{}, ErrLabelCounterClosed
+	}
+	if over {
+		return decoded{}, ErrLabelLimit
+	}
+
+	values := make([]T, 0, len(labels))
+	release := func() {
+		for _, c := range values {
+			counter.rel(c)
+		}
+	}
+
+	seen := make(map[string]struct{}, len(labels))
+	for i, label := range labels {
+		if _, ok := seen[label]; ok {
+			release()
+			return decoded{}, ErrInvalidEncoding
+		}
+		seen[label] = struct{}{}
+
+		c := counter.acq(label)
+		values = append(values, c)
+		if err := set(i, c); err != nil {
+			release()
+			return decoded{}, err
+		}
+	}
+
+	return decoded{
+		apply: func() error {
+			if err := counter.replace(labels, values); err != nil {
+				release()
+				return err
+			}
+
+			return nil
+		},
+		discard: release,
+	}, nil
+}
+
+// replace replaces all labels and counters under the lock,
+// the old counters are released using the rel function.
+// It fails with ErrLabelLimit if there are more labels than the limit,
+// whatever the policy is.
+func (counter *LabelCounter[T]) replace(labels []string, values []T) error {
+	counter.mux.Lock()
+	defer counter.mux.Unlock()
+
+	if counter.closed {
+		return ErrLabelCounterClosed
+	}
+
+	if counter.overLimit(labels) {
+		return ErrLabelLimit
+	}
+
+	counter.reset()
+
+	now := counter.now()
+	counter.value = values
+	counter.touched = make([]int64, len(values))
+	counter.created = make([]int64, len(values))
+	counter.labels = make(map[string]int, len(labels))
+	counter.entries = make(map[int]string, len(labels))
+	for idx, label := range labels {
+		counter.touched[idx] = now
+		counter.created[idx] = now
+		counter.labels[label] = idx
+		counter.entries[idx] = label
+	}
+
+	counter.sorted = append([]string(nil), labels...)
+	sort.Strings(counter.sorted)
+
+	for _, label := range labels {
+		for _, observer := range counter.observers {
+			observer.OnLabelCreated(label)
+		}
+	}
+
+	return nil
+}
+
+// overLimit say there are more labels than the limit,
+// the lock must be held.
+func (counter *LabelCounter[T]) overLimit(labels []string) bool {
+	if counter.limit <= 0 {
+		return false
+	}
+
+	// the overflow label is not counted, as in getLabel
+	n := 0
+	for _, label := range labels {
+		if label != counter.overflowLabel {
+			n++
+		}
+	}
+
+	return n > counter.limit
+}
+
+// MarshalJSON implements json.Marshaler.
+// Counters not implementing json.Marshaler are written as their real values.
+func (counter *LabelCounter[T]) MarshalJSON() ([]byte, error) {
+	v := labelCounterJSON{Labels: make(map[string]json.RawMessage)}
+	if err := counter.jsonLabels(v.Labels); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(v)
+}
+
+// jsonLabels adds the JSON of the counter of each label to labels.
+func (counter *LabelCounter[T]) jsonLabels(labels map[string]json.RawMessage) error {
+	return counter.sortedRange(func(label string, c T) (err error) {
+		if m, ok := any(c).(json.Marshaler); ok {
+			labels[label], err = m.MarshalJSON()
+		} else {
+			labels[label], err = jsonFloat(realOf(c)).MarshalJSON()
+		}
+
+		return
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+// It replaces all labels, the counters are created by the acquire function.
+// Nothing is changed if it fails.
+func (counter *LabelCounter[T]) UnmarshalJSON(data []byte) error {
+	labels, raws, err := decodeJSONLabels(data)
+	if err != nil {
+		return err
+	}
+
+	return counter.restore(labels, setJSON[T](raws))
+}
+
+// decodeJSONLabels returns the sorted labels and their JSON.
+func decodeJSONLabels(data []byte) (labels []string, raws []json.RawMessage, err error) {
+	var v labelCounterJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, nil, err
+	}
+
+	labels = make([]string, 0, len(v.Labels))
+	for label := range v.Labels {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	raws = make([]json.RawMessage, len(labels))
+	for i, label := range labels {
+		raws[i] = v.Labels[label]
+	}
+
+	return labels, raws, nil
+}
+
+// setJSON returns a function setting the counter of the i-th label from raws.
+func setJSON[T Gounter](raws []json.RawMessage) func(i int, c T) error {
+	return func(i int, c T) error {
+		if u, ok := any(c).(json.Unmarshaler); ok {
+			return u.UnmarshalJSON(raws[i])
+		}
+
+		var f jsonFloat
+		if err := f.UnmarshalJSON(raws[i]); err != nil {
+			return err
+		}
+		c.Set(float64(f))
+
+		return nil
+	}
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+// Counters not implementing encoding.BinaryMarshaler are written as their real values.
+func (counter *LabelCounter[T]) MarshalBinary() ([]byte, error) {
+	labels := make(map[string][]byte)
+	if err := counter.binaryLabels(labels); err != nil {
+		return nil, err
+	}
+
+	return encodeBinaryLabels(labels), nil
+}
+
+// binaryLabels adds the binary encoding of the counter of each label to labels.
+func (counter *LabelCounter[T]) binaryLabels(labels map[string][]byte) error {
+	return counter.sortedRange(func(label string, c T) error {
+		if m, ok := any(c).(encoding.BinaryMarshaler); ok {
+			data, err := m.MarshalBinary()
+			if err != nil {
+				return err
+			}
+
+			labels[label] = data
+			return nil
+		}
+
+		labels[label] = appendFloat(nil, realOf(c))
+		return nil
+	})
+}
+
+// encodeBinaryLabels returns the binary encoding of the labels, in order.
+func encodeBinaryLabels(labels map[string][]byte) []byte {
+	sorted := make([]string, 0, len(labels))
+	for label := range labels {
+		sorted = append(sorted, label)
+	}
+	sort.Strings(sorted)
+
+	b := []byte{marshalVersion, kindLabelCounter}
+	b = appendUvarint(b, uint64(len(sorted)))
+	for _, label := range sorted {
+		b = appendBytes(b, []byte(label))
+		b = appendBytes(b, labels[label])
+	}
+
+	return b
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+// It replaces all labels, the counters are created by the acquire function.
+// Nothing is changed if it fails.
+func (counter *LabelCounter[T]) UnmarshalBinary(data []byte) error {
+	dec, err := counter.decodeBinary(data)
+	if err != nil {
+		return err
+	}
+
+	return dec.apply()
+}
+
+// decodeBinary decodes data without changing the LabelCounter,
+// apply replaces the labels.
+func (counter *LabelCounter[T]) decodeBinary(data []byte) (decoded, error) {
+	labels, values, err := decodeBinaryLabels(data)
+	if err != nil {
+		return decoded{}, err
+	}
+
+	return counter.decodeLabels(labels, setBinary[T](values))
+}
+
+// decodeBinaryLabels returns the labels and the encoding of their counters.
+func decodeBinaryLabels(data []byte) (labels []string, values [][]byte, err error) {
+	d := newDecoder(data, kindLabelCounter)
+	n := d.uvarint()
+
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		labels = append(labels, string(d.bytes()))
+		values = append(values, d.bytes())
+	}
+	if err := d.done(); err != nil {
+		return nil, nil, err
+	}
+
+	return labels, values, nil
+}
+
+// setBinary returns a function setting the counter of the i-th label from values.
+func setBinary[T Gounter](values [][]byte) func(i int, c T) error {
+	return func(i int, c T) error {
+		if u, ok := any(c).(encoding.BinaryUnmarshaler); ok {
+			return u.UnmarshalBinary(values[i])
+		}
+
+		v := &decoder{data: values[i]}
+		f := v.float()
+		if err := v.done(); err != nil {
+			return err
+		}
+		c.Set(f)
+
+		return nil
+	}
+}
+
+// MarshalJSON implements json.Marshaler, the labels of all shards are written
+// as LabelCounter does.
+func (counter *ShardedLabelCounter[T]) MarshalJSON() ([]byte, error) {
+	v := labelCounterJSON{Labels: make(map[string]json.RawMessage)}
+	for _, shard := range counter.shards {
+		if err := shard.jsonLabels(v.Labels); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+// It replaces all labels of all shards.
+// Nothing is changed if it fails, unless a shard is closed at the same time.
+func (counter *ShardedLabelCounter[T]) UnmarshalJSON(data []byte) error {
+	labels, raws, err := decodeJSONLabels(data)
+	if err != nil {
+		return err
+	}
+
+	dec, err := counter.decodeLabels(labels, setJSON[T](raws))
+	if err != nil {
+		return err
+	}
+
+	return dec.apply()
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler,
+// the encoding is the same as LabelCounter.
+func (counter *ShardedLabelCounter[T]) MarshalBinary() ([]byte, error) {
+	labels := make(map[string][]byte)
+	for _, shard := range counter.shards {
+		if err := shard.binaryLabels(labels); err != nil {
+			return nil, err
+		}
+	}
+
+	return encodeBinaryLabels(labels), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+// It replaces all labels of all shards.
+// Nothing is changed if it fails, unless a shard is closed at the same time.
+func (counter *ShardedLabelCounter[T]) UnmarshalBinary(data []byte) error {
+	dec, err := counter.decodeBinary(data)
+	if err != nil {
+		return err
+	}
+
+	return dec.apply()
+}
+
+// decodeBinary decodes data without changing the ShardedLabelCounter,
+// apply replaces the labels of all shards.
+func (counter *ShardedLabelCounter[T]) decodeBinary(data []byte) (decoded, error) {
+	labels, values, err := decodeBinaryLabels(data)
+	if err != nil {
+		return decoded{}, err
+	}
+
+	return counter.decodeLabels(labels, setBinary[T](values))
+}
+
+// decodeLabels splits the labels by shard and decodes them in every shard,
+// set is called with the index in labels.
+func (counter *ShardedLabelCounter[T]) decodeLabels(labels []string, set func(i int, c T) error) (decoded, error) {
+	groups := make([][]int, len(counter.shards))
+	for i, label := range labels {
+		s := counter.shardOf(label)
+		groups[s] = append(groups[s], i)
+	}
+
+	decodes := make([]decoded, 0, len(counter.shards))
+	for s, group := range groups {
+		group := group
+		shardLabels := make([]string, len(group))
+		for j, i := range group {
+			shardLabels[j] = labels[i]
+		}
+
+		dec, err := counter.shards[s].decodeLabels(shardLabels, func(j int, c T) error {
+			return set(group[j], c)
+		})
+		if err != nil {
+			discardAll(decodes)
+			return decoded{}, err
+		}
+		decodes = append(decodes, dec)
+	}
+
+	return decoded{
+		apply: func() error {
+			for i, dec := range decodes {
+				if err := dec.apply(); err != nil {
+					discardAll(decodes[i+1:])
+					return err
+				}
+			}
+
+			return nil
+		},
+		discard: func() {
+			discardAll(decodes)
+		},
+	}, nil
+}
+
+// MarshalJSON implements json.Marshaler,
+// ErrUnsupportedCounter is returned if the wrapped Gounter does not implement it.
+func (o *ObservedGounter) MarshalJSON() ([]byte, error) {
+	m, ok := o.counter.(json.Marshaler)
+	if !ok {
+		return nil, ErrUnsupportedCounter
+	}
+
+	return m.MarshalJSON()
+}
+
+// UnmarshalJSON implements json.Unmarshaler, the observers are notified.
+// ErrUnsupportedCounter is returned if the wrapped Gounter does not implement it.
+func (o *ObservedGounter) UnmarshalJSON(data []byte) error {
+	u, ok := o.counter.(json.Unmarshaler)
+	if !ok {
+		return ErrUnsupportedCounter
+	}
+
+	var err error
+	o.observe(func() bool {
+		err = u.UnmarshalJSON(data)
+		return err == nil
+	})
+
+	return err
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler,
+// ErrUnsupportedCounter is returned if the wrapped Gounter does not implement it.
+func (o *ObservedGounter) MarshalBinary() ([]byte, error) {
+	m, ok := o.counter.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, ErrUnsupportedCounter
+	}
+
+	return m.MarshalBinary()
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, the observers are notified.
+// ErrUnsupportedCounter is returned if the wrapped Gounter does not implement it.
+func (o *ObservedGounter) UnmarshalBinary(data []byte) error {
+	dec, err := o.decodeBinary(data)
+	if err != nil {
+		return err
+	}
+
+	return dec.apply()
+}
+
+// decodeBinary decodes data without changing the wrapped Gounter,
+// apply sets the decoded state and notifies the observers.
+func (o *ObservedGounter) decodeBinary(data []byte) (decoded, error) {
+	var dec decoded
+	switch u := o.counter.(type) {
+	case binaryDecoder:
+		var err error
+		if dec, err = u.decodeBinary(data); err != nil {
+			return decoded{}, err
+		}
+	case encoding.BinaryUnmarshaler:
+		// not checked before apply
+		dec.apply = func() error {
+			return u.UnmarshalBinary(data)
+		}
+	default:
+		return decoded{}, ErrUnsupportedCounter
+	}
+
+	apply := dec.apply
+	dec.apply = func() (err error) {
+		o.observe(func() bool {
+			err = apply()
+			return err == nil
+		})
+
+		return
+	}
+
+	return dec, nil
+}
@@ -0,0 +1,468 @@
+package gounter
+
+import (
+	"encoding"
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestCounterMarshal(t *testing.T) {
+	t.Parallel()
+
+	for _, v := range []float64{0, 1.5, -3, math.Inf(1), math.Inf(-1), math.MaxFloat64} {
+		c := AcquireCounter()
+		c.Set(v)
+
+		data, err := json.Marshal(c)
+		if err != nil {
+			t.Fatalf("should be nil, but %v", err)
+		}
+		got := AcquireCounter()
+		if err := json.Unmarshal(data, got); err != nil || got.Real() != v {
+			t.Errorf("%s, should be %f, but %f, %v", data, v, got.Real(), err)
+		}
+
+		data, _ = c.MarshalBinary()
+		got.Reset()
+		if err := got.UnmarshalBinary(data); err != nil || got.Real() != v {
+			t.Errorf("should be %f, but %f, %v", v, got.Real(), err)
+		}
+
+		ReleaseCounter(c)
+		ReleaseCounter(got)
+	}
+
+	c := AcquireCounter()
+	defer ReleaseCounter(c)
+	c.Set(math.NaN())
+
+	data, _ := json.Marshal(c)
+	if string(data) != `{"value":"NaN"}` {
+		t.Fatalf("wrong json, got %s", data)
+	}
+	c.Reset()
+	if err := json.Unmarshal(data, c); err != nil || !math.IsNaN(c.Real()) {
+		t.Fatalf("should be NaN, but %f, %v", c.Real(), err)
+	}
+
+	c.Set(2)
+	data, _ = json.Marshal(c)
+	if string(data) != `{"value":2}` {
+		t.Fatalf("wrong json, got %s", data)
+	}
+	if err := json.Unmarshal([]byte(`{"value":"x"}`), c); err != ErrInvalidEncoding {
+		t.Fatalf("should be %v, but %v", ErrInvalidEncoding, err)
+	}
+}
+
+func TestMaxCounterMarshal(t *testing.T) {
+	t.Parallel()
+
+	c := AcquireRangeCounter(-5, 10)
+	defer ReleaseMaxCounter(c)
+	c.SetPolicy(OverflowAllow)
+	c.Add(12)
+
+	check := func(got *MaxCounter) {
+		t.Helper()
+
+		if got.Real() != 12 || got.GetMax() != 10 || got.GetMin() != -5 {
+			t.Errorf("wrong state, got %f %f %f", got.Real(), got.GetMax(), got.GetMin())
+		}
+		if got.Can() || !got.CanSub() {
+			t.Errorf("should be done, got %v %v", got.Can(), got.CanSub())
+		}
+		if got.GetPolicy() != OverflowAllow {
+			t.Errorf("should be %d, but %d", OverflowAllow, got.GetPolicy())
+		}
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+	want := `{"value":12,"max":10,"min":-5,"done":true,"min_done":false,"policy":2}`
+	if string(data) != want {
+		t.Fatalf("should be %s, but %s", want, data)
+	}
+
+	// not from pool
+	got := &MaxCounter{}
+	if err := json.Unmarshal(data, got); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+	check(got)
+
+	data, _ = c.MarshalBinary()
+	got = AcquireMaxCounter(1)
+	defer ReleaseMaxCounter(got)
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+	check(got)
+
+	// unknown policy
+	if err := json.Unmarshal([]byte(`{"value":1,"max":10,"policy":9}`), got); err != ErrInvalidEncoding {
+		t.Fatalf("should be %v, but %v", ErrInvalidEncoding, err)
+	}
+	check(got)
+
+	// undone after restore
+	c.Set(1)
+	c.setUnDone()
+	data, _ = c.MarshalBinary()
+	if err := got.UnmarshalBinary(data); err != nil || !got.Can() || got.Get() != 1 {
+		t.Fatalf("should be undone, got %v %f %v", got.Can(), got.Get(), err)
+	}
+}
+
+func TestLabelCounterMarshal(t *testing.T) {
+	t.Parallel()
+
+	c := NewLabelCounterNormal()
+	c.Add("b", 2)
+	c.Add("a", 1.5)
+	c.Add("", 3)
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+	want := `{"labels":{"":{"value":3},"a":{"value":1.5},"b":{"value":2}}}`
+	if string(data) != want {
+		t.Fatalf("should be %s, but %s", want, data)
+	}
+
+	got := NewLabelCounterNormal()
+	got.Add("old", 1)
+	if err := json.Unmarshal(data, got); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+	testLabelSnapshot(t, got.Snapshot(), map[string]float64{"": 3, "a": 1.5, "b": 2})
+
+	data, _ = c.MarshalBinary()
+	got = NewLabelCounterNormal()
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+	testLabelSnapshot(t, got.Snapshot(), map[string]float64{"": 3, "a": 1.5, "b": 2})
+
+	// same encoding after round trip
+	again, _ := got.MarshalBinary()
+	if string(again) != string(data) {
+		t.Fatal("should be same encoding")
+	}
+}
+
+func TestLabelCounterMarshalMax(t *testing.T) {
+	t.Parallel()
+
+	c := NewLabelCounterWithMax(5)
+	c.Add("a", 5)
+	c.Inc("a") // done
+	SetLabelMax(c, "b", 100)
+	c.Add("b", 7)
+
+	for _, codec := range []struct {
+		marshal   func() ([]byte, error)
+		unmarshal func(*LabelCounter[*MaxCounter], []byte) error
+	}{
+		{c.MarshalJSON, (*LabelCounter[*MaxCounter]).UnmarshalJSON},
+		{c.MarshalBinary, (*LabelCounter[*MaxCounter]).UnmarshalBinary},
+	} {
+		data, err := codec.marshal()
+		if err != nil {
+			t.Fatalf("should be nil, but %v", err)
+		}
+
+		got := NewLabelCounterWithMax(1)
+		if err := codec.unmarshal(got, data); err != nil {
+			t.Fatalf("should be nil, but %v", err)
+		}
+
+		_, a := got.Get("a")
+		_, b := got.Get("b")
+		if a.Get() != 5 || a.Can() || b.Get() != 7 || b.GetMax() != 100 {
+			t.Errorf("wrong state, got %f %v %f %f", a.Get(), a.Can(), b.Get(), b.GetMax())
+		}
+	}
+}
+
+func TestLabelCounterMarshalValues(t *testing.T) {
+	t.Parallel()
+
+	// only the real values are written without encoding
+	newCounter := func() *LabelCounter[valueGounter] {
+		return NewLabelCounter[valueGounter](func() valueGounter {
+			return valueGounter{AcquireCounter()}
+		}, func(valueGounter) {})
+	}
+	real := func(c *LabelCounter[valueGounter]) float64 {
+		_, g := c.Get("x")
+		return g.Real()
+	}
+
+	c := newCounter()
+	c.Add("x", -5)
+
+	data, _ := json.Marshal(c)
+	if string(data) != `{"labels":{"x":-5}}` {
+		t.Fatalf("wrong json, got %s", data)
+	}
+
+	got := newCounter()
+	if err := json.Unmarshal(data, got); err != nil || real(got) != -5 {
+		t.Fatalf("should be %d, but %f, %v", -5, real(got), err)
+	}
+
+	data, _ = c.MarshalBinary()
+	got = newCounter()
+	if err := got.UnmarshalBinary(data); err != nil || real(got) != -5 {
+		t.Fatalf("should be %d, but %f, %v", -5, real(got), err)
+	}
+}
+
+func TestCountersMarshalExact(t *testing.T) {
+	t.Parallel()
+
+	exact := int64(1)<<53 + 1
+
+	i := AcquireInt64Counter()
+	defer ReleaseInt64Counter(i)
+	i.SetInt(exact)
+	sharded := AcquireShardedCounter()
+	defer ReleaseShardedCounter(sharded)
+	sharded.Set(-2.5)
+	precise := AcquirePreciseCounter()
+	defer ReleasePreciseCounter(precise)
+	for n := 0; n < 10; n++ {
+		precise.Add(0.1)
+	}
+	typed := NewTypedCounter[uint64]()
+	typed.Set(1<<64 - 1)
+
+	tests := []struct {
+		c    Gounter
+		new  func() Gounter
+		real func(Gounter) float64
+	}{
+		{i, func() Gounter { return &Int64Counter{} }, func(c Gounter) float64 { return float64(c.(*Int64Counter).RealInt() - exact) }},
+		{sharded, func() Gounter { return AcquireShardedCounter() }, realOf},
+		{precise, func() Gounter { return &PreciseCounter{} }, realOf},
+		{AsGounter[uint64](typed), func() Gounter { return AsGounter[uint64](NewTypedCounter[uint64]()) }, realOf},
+	}
+	for _, tt := range tests {
+		want := tt.real(tt.c)
+
+		data, err := json.Marshal(tt.c)
+		if err != nil {
+			t.Fatalf("should be nil, but %v", err)
+		}
+		got := tt.new()
+		if err := json.Unmarshal(data, got); err != nil || tt.real(got) != want {
+			t.Errorf("%s, should be %f, but %f, %v", data, want, tt.real(got), err)
+		}
+
+		data, err = tt.c.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			t.Fatalf("should be nil, but %v", err)
+		}
+		got = tt.new()
+		if err := got.(encoding.BinaryUnmarshaler).UnmarshalBinary(data); err != nil || tt.real(got) != want {
+			t.Errorf("%T, should be %f, but %f, %v", got, want, tt.real(got), err)
+		}
+	}
+
+	// exact uint64
+	got := NewTypedCounter[uint64]()
+	data, _ := json.Marshal(typed)
+	if err := json.Unmarshal(data, got); err != nil || got.Real() != 1<<64-1 {
+		t.Fatalf("%s, should be %d, but %d, %v", data, uint64(1<<64-1), got.Real(), err)
+	}
+
+	// exact in LabelCounter
+	c := NewLabelCounterInt64()
+	_, a := c.Add("a", 0)
+	a.SetInt(exact)
+	c.Add("b", -5)
+	for _, codec := range []struct {
+		marshal   func() ([]byte, error)
+		unmarshal func(*LabelCounter[*Int64Counter], []byte) error
+	}{
+		{c.MarshalJSON, (*LabelCounter[*Int64Counter]).UnmarshalJSON},
+		{c.MarshalBinary, (*LabelCounter[*Int64Counter]).UnmarshalBinary},
+	} {
+		data, _ := codec.marshal()
+		got := NewLabelCounterInt64()
+		if err := codec.unmarshal(got, data); err != nil {
+			t.Fatalf("should be nil, but %v", err)
+		}
+
+		_, a := got.Get("a")
+		_, b := got.Get("b")
+		if a.RealInt() != exact || b.RealInt() != -5 {
+			t.Errorf("should be %d and -5, but %d and %d", exact, a.RealInt(), b.RealInt())
+		}
+	}
+
+	// another type of TypedCounter
+	data, _ = typed.MarshalBinary()
+	if err := NewTypedCounter[int64]().UnmarshalBinary(data); err != ErrInvalidEncoding {
+		t.Fatalf("should be %v, but %v", ErrInvalidEncoding, err)
+	}
+}
+
+func TestLabelCounterUnmarshalErrors(t *testing.T) {
+	t.Parallel()
+
+	c := NewLabelCounterNormal()
+	c.Add("a", 1)
+	c.Add("b", 2)
+	data, _ := c.MarshalBinary()
+
+	limited := NewLabelCounterNormal()
+	limited.SetLabelLimit(1, LabelLimitReject)
+	if err := limited.UnmarshalBinary(data); err != ErrLabelLimit {
+		t.Errorf("should be %v, but %v", ErrLabelLimit, err)
+	}
+
+	closed := NewLabelCounterNormal()
+	closed.Close()
+	if err := closed.UnmarshalBinary(data); err != ErrLabelCounterClosed {
+		t.Errorf("should be %v, but %v", ErrLabelCounterClosed, err)
+	}
+
+	// nothing changed on bad data
+	got := NewLabelCounterNormal()
+	got.Add("keep", 1)
+	if err := got.UnmarshalBinary(data[:len(data)-1]); err != ErrInvalidEncoding {
+		t.Errorf("should be %v, but %v", ErrInvalidEncoding, err)
+	}
+	testLabelSnapshot(t, got.Snapshot(), map[string]float64{"keep": 1})
+
+	// a bad entry after a good one
+	got.Set("keep", 7)
+	err := json.Unmarshal([]byte(`{"labels":{"a":{"value":1},"b":{"value":"oops"}}}`), got)
+	if err != ErrInvalidEncoding {
+		t.Errorf("should be %v, but %v", ErrInvalidEncoding, err)
+	}
+	testLabelSnapshot(t, got.Snapshot(), map[string]float64{"keep": 7})
+
+	// duplicate labels
+	dup := []byte{marshalVersion, kindLabelCounter, 2}
+	for i := 0; i < 2; i++ {
+		dup = appendBytes(dup, []byte("a"))
+		dup = appendBytes(dup, counterData(t, 1))
+	}
+	if err := got.UnmarshalBinary(dup); err != ErrInvalidEncoding {
+		t.Errorf("should be %v, but %v", ErrInvalidEncoding, err)
+	}
+	testLabelSnapshot(t, got.Snapshot(), map[string]float64{"keep": 7})
+}
+
+// counterData returns the binary encoding of a Counter with the value.
+func counterData(t *testing.T, v float64) []byte {
+	t.Helper()
+
+	c := AcquireCounter()
+	defer ReleaseCounter(c)
+	c.Set(v)
+
+	data, err := c.MarshalBinary()
+	if err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+
+	return data
+}
+
+func TestLabelCounterUnmarshalRelease(t *testing.T) {
+	t.Parallel()
+
+	acquired, released := 0, 0
+	c := NewLabelCounter[*Counter](func() *Counter {
+		acquired++
+		return AcquireCounter()
+	}, func(counter *Counter) {
+		released++
+		ReleaseCounter(counter)
+	})
+	c.Add("old", 1)
+
+	// the new counters are released on error
+	if err := json.Unmarshal([]byte(`{"labels":{"a":{"value":1},"b":{"value":"oops"}}}`), c); err != ErrInvalidEncoding {
+		t.Fatalf("should be %v, but %v", ErrInvalidEncoding, err)
+	}
+	if acquired != 3 || released != 2 {
+		t.Fatalf("should be 3 and 2, but %d and %d", acquired, released)
+	}
+
+	// the old counters are released on success
+	if err := json.Unmarshal([]byte(`{"labels":{"a":{"value":1}}}`), c); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+	if acquired != 4 || released != 3 {
+		t.Fatalf("should be 4 and 3, but %d and %d", acquired, released)
+	}
+	testLabelSnapshot(t, c.Snapshot(), map[string]float64{"a": 1})
+}
+
+func TestUnmarshalBinaryErrors(t *testing.T) {
+	t.Parallel()
+
+	c := AcquireCounter()
+	defer ReleaseCounter(c)
+	m := AcquireMaxCounter(1)
+	defer ReleaseMaxCounter(m)
+	l := NewLabelCounterNormal()
+
+	counterData, _ := c.MarshalBinary()
+	maxData, _ := m.MarshalBinary()
+
+	tests := []struct {
+		u    encoding.BinaryUnmarshaler
+		data []byte
+		err  error
+	}{
+		{c, nil, ErrInvalidEncoding},
+		{c, []byte{marshalVersion}, ErrInvalidEncoding},
+		{c, []byte{2, kindCounter, 0, 0, 0, 0, 0, 0, 0, 0}, ErrUnsupportedVersion},
+		{c, counterData[:5], ErrInvalidEncoding},
+		{c, append(counterData, 0), ErrInvalidEncoding},
+		{c, maxData, ErrInvalidEncoding},
+		{m, counterData, ErrInvalidEncoding},
+		{m, append(maxData[:len(maxData)-1], 9), ErrInvalidEncoding},
+		{l, []byte{marshalVersion, kindLabelCounter, 1, 5, 'a'}, ErrInvalidEncoding},
+		{l, []byte{marshalVersion, kindLabelCounter, 0xff}, ErrInvalidEncoding},
+	}
+	for i, tt := range tests {
+		if err := tt.u.UnmarshalBinary(tt.data); err != tt.err {
+			t.Errorf("%d, should be %v, but %v", i, tt.err, err)
+		}
+	}
+}
+
+// valueGounter is a Gounter without encoding.
+type valueGounter struct {
+	Gounter
+}
+
+// Real returns the real value of the Gounter.
+func (g valueGounter) Real() float64 {
+	return realOf(g.Gounter)
+}
+
+// testLabelSnapshot compares the snapshot.
+func testLabelSnapshot(t *testing.T, got, want map[string]float64) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("should be %v, but %v", want, got)
+	}
+	for label, v := range want {
+		if got[label] != v {
+			t.Fatalf("should be %v, but %v", want, got)
+		}
+	}
+}
@@ -1,20 +1,58 @@
 package gounter
 
 import (
+	"container/list"
+	"context"
 	"math"
 	"sync"
 	"sync/atomic"
 )
 
+// OverflowPolicy decides what MaxCounter does
+// when a delta would move the counter across its bounds.
+type OverflowPolicy uint32
+
+const (
+	// OverflowReject rejects the delta, it is the default policy.
+	OverflowReject OverflowPolicy = iota
+	// OverflowClamp applies the part of the delta that fits in the bounds.
+	OverflowClamp
+	// OverflowAllow applies the delta in full,
+	// and flags the counter as done while it is over max.
+	OverflowAllow
+)
+
+// done flags of MaxCounter.
+const (
+	doneMax uint32 = 1 << iota
+	doneMin
+)
+
 // MaxCounter has a max number for counter.
 // When counter to max number, it will stop and reject all other actions.
+//
+// It also has a min number, 0 by default.
+// The min edge has the same done/undone semantics as the max edge.
 type MaxCounter struct {
 	noCopy noCopy
 
 	counter *Counter
 
 	done    uint32
+	policy  uint32
 	maxBits uint64
+	minBits uint64
+
+	// waiters of Acquire, in FIFO order.
+	waiting int32
+	waiters list.List
+	waitMux sync.Mutex
+}
+
+// maxWaiter is a goroutine blocked in Acquire.
+type maxWaiter struct {
+	n     float64
+	ready chan struct{}
 }
 
 // maxCounterPool
@@ -33,6 +71,14 @@ func AcquireMaxCounter(max float64) *MaxCounter {
 	return maxCounter
 }
 
+// AcquireRangeCounter acquire a MaxCounter with min and max from pool.
+func AcquireRangeCounter(min, max float64) *MaxCounter {
+	maxCounter := AcquireMaxCounter(max)
+	maxCounter.SetMin(min)
+
+	return maxCounter
+}
+
 // ReleaseMaxCounter releases MaxCounter.
 func ReleaseMaxCounter(c *MaxCounter) {
 	if c == nil {
@@ -40,6 +86,7 @@ func ReleaseMaxCounter(c *MaxCounter) {
 	}
 
 	c.reset()
+	c.SetPolicy(OverflowReject)
 	ReleaseCounter(c.counter)
 	c.counter = nil
 	maxCounterPool.Put(c)
@@ -54,24 +101,50 @@ func (c *MaxCounter) reset() {
 		c.counter = AcquireCounter()
 	}
 	atomic.StoreUint64(&c.maxBits, 0)
+	atomic.StoreUint64(&c.minBits, 0)
 	atomic.StoreUint32(&c.done, 0)
 }
 
 // isDone say now is max?
 func (c *MaxCounter) isDone() bool {
-	done := atomic.LoadUint32(&c.done)
-
-	return done != 0
+	return c.isFlagged(doneMax)
 }
 
 // setDone set add done, now is max.
 func (c *MaxCounter) setDone() {
-	atomic.StoreUint32(&c.done, 1)
+	c.setFlag(doneMax, true)
 }
 
-// setUnDone set done value = 0.
+// setUnDone clears the max done flag.
 func (c *MaxCounter) setUnDone() {
-	atomic.StoreUint32(&c.done, 0)
+	c.setFlag(doneMax, false)
+}
+
+// isMinDone say now is min?
+func (c *MaxCounter) isMinDone() bool {
+	return c.isFlagged(doneMin)
+}
+
+// isFlagged checks the done flag.
+func (c *MaxCounter) isFlagged(flag uint32) bool {
+	done := atomic.LoadUint32(&c.done)
+
+	return done&flag != 0
+}
+
+// setFlag sets or clears the done flag.
+func (c *MaxCounter) setFlag(flag uint32, on bool) {
+	for {
+		old := atomic.LoadUint32(&c.done)
+		done := old &^ flag
+		if on {
+			done = old | flag
+		}
+
+		if old == done || atomic.CompareAndSwapUint32(&c.done, old, done) {
+			return
+		}
+	}
 }
 
 // Can use add?
@@ -79,6 +152,11 @@ func (c *MaxCounter) Can() bool {
 	return !c.isDone()
 }
 
+// CanSub use sub?
+func (c *MaxCounter) CanSub() bool {
+	return !c.isMinDone()
+}
+
 // GetMax gets a max number.
 func (c *MaxCounter) GetMax() float64 {
 	bits := atomic.LoadUint64(&c.maxBits)
@@ -88,31 +166,78 @@ func (c *MaxCounter) GetMax() float64 {
 }
 
 // SetMax set a max number.
+// The max done flag is cleared if the value is below the new max.
 func (c *MaxCounter) SetMax(max float64) {
 	for {
 		oldBits := atomic.LoadUint64(&c.maxBits)
 		newBits := math.Float64bits(max)
 
 		if atomic.CompareAndSwapUint64(&c.maxBits, oldBits, newBits) {
+			if c.counter != nil && c.Real() < max {
+				c.setUnDone()
+			}
+			c.notifyWaiters()
 			return
 		}
 	}
 }
 
+// GetMin gets a min number.
+func (c *MaxCounter) GetMin() float64 {
+	bits := atomic.LoadUint64(&c.minBits)
+	min := math.Float64frombits(bits)
+
+	return min
+}
+
+// SetMin set a min number.
+// The min done flag is cleared if the value is above the new min.
+func (c *MaxCounter) SetMin(min float64) {
+	atomic.StoreUint64(&c.minBits, math.Float64bits(min))
+	if c.counter != nil && c.Real() > min {
+		c.setFlag(doneMin, false)
+	}
+}
+
+// GetPolicy gets the OverflowPolicy.
+func (c *MaxCounter) GetPolicy() OverflowPolicy {
+	return OverflowPolicy(atomic.LoadUint32(&c.policy))
+}
+
+// SetPolicy sets the OverflowPolicy.
+func (c *MaxCounter) SetPolicy(policy OverflowPolicy) {
+	atomic.StoreUint32(&c.policy, uint32(policy))
+}
+
 // Set sets the value of the counter to the given value,
-// if it is less than or equal to the maximum value
+// if it is between the minimum and the maximum value.
 func (c *MaxCounter) Set(value float64) bool {
 	max := c.GetMax()
 	if max < value {
 		return false
 	}
 
-	return c.counter.Set(value)
+	min := c.GetMin()
+	if value < min {
+		return false
+	}
+
+	ok := c.counter.Set(value)
+	c.notifyWaiters()
+
+	return ok
 }
 
 // Get a number.
+// When the counter value is less than min, it returns min.
 func (c *MaxCounter) Get() float64 {
-	return c.counter.Get()
+	val := c.Real()
+	min := c.GetMin()
+	if val < min {
+		return min
+	}
+
+	return val
 }
 
 // Real get Counter Real().
@@ -123,31 +248,97 @@ func (c *MaxCounter) Real() float64 {
 // Reset reset MaxCounter.
 func (c *MaxCounter) Reset() {
 	c.reset()
+	c.notifyWaiters()
 }
 
-// Add is same as Counter.Add().
+// Add is same as Counter.Add(),
+// but the bound check and the update are done in a single CAS.
+// What happens when the delta crosses the bounds is decided by the OverflowPolicy.
 func (c *MaxCounter) Add(delta float64) bool {
-	if c.isDone() && delta >= 0 {
-		return false
-	}
+	_, ok := c.AddApplied(delta)
+	return ok
+}
 
-	if c.isDone() && delta < 0 {
-		c.setUnDone()
-	}
+// AddApplied is same as Add,
+// and also returns the amount actually applied to the counter.
+// The amount is less than delta only with OverflowClamp.
+func (c *MaxCounter) AddApplied(delta float64) (applied float64, ok bool) {
+	policy := c.GetPolicy()
 
-	max := c.GetMax()
-	realNum := c.Real()
+	if policy != OverflowAllow {
+		if c.isDone() && delta >= 0 {
+			return 0, false
+		}
 
-	if realNum >= max && delta > 0 {
-		c.setDone()
-		return false
-	}
+		if c.isMinDone() && delta <= 0 {
+			return 0, false
+		}
 
-	if realNum <= 0 && delta < 0 {
-		return false
+		if c.isDone() && delta < 0 {
+			c.setUnDone()
+		}
+
+		if c.isMinDone() && delta > 0 {
+			c.setFlag(doneMin, false)
+		}
 	}
 
-	return c.counter.Add(delta)
+	for {
+		max := c.GetMax()
+		min := c.GetMin()
+		oldBits := atomic.LoadUint64(&c.counter.bits)
+		oldVal := math.Float64frombits(oldBits)
+		newVal := oldVal + delta
+
+		switch policy {
+		case OverflowClamp:
+			if newVal > max && delta > 0 {
+				if oldVal >= max {
+					c.setDone()
+					return 0, false
+				}
+				newVal = max
+			}
+			if newVal < min && delta < 0 {
+				if oldVal <= min {
+					c.setFlag(doneMin, true)
+					return 0, false
+				}
+				newVal = min
+			}
+		case OverflowAllow:
+		default:
+			// done only at the bound, a smaller delta may still fit
+			if newVal > max && delta > 0 {
+				if oldVal >= max {
+					c.setDone()
+				}
+				return 0, false
+			}
+			if newVal < min && delta < 0 {
+				if oldVal <= min {
+					c.setFlag(doneMin, true)
+				}
+				return 0, false
+			}
+		}
+
+		newBits := math.Float64bits(newVal)
+		if atomic.CompareAndSwapUint64(&c.counter.bits, oldBits, newBits) {
+			c.counter.changed(newVal)
+
+			if policy == OverflowAllow {
+				c.setFlag(doneMax, newVal > max)
+				c.setFlag(doneMin, newVal < min)
+			}
+
+			if newVal < oldVal {
+				c.notifyWaiters()
+			}
+
+			return newVal - oldVal, true
+		}
+	}
 }
 
 // Sub is same as Counter.Sub().
@@ -201,8 +392,126 @@ func (c *MaxCounter) CopyTo(d interface{}) (ok bool, err error) {
 		dst.counter = oldCounter
 
 		if atomic.CompareAndSwapUint64(&dst.maxBits, oldMaxBits, bits) {
+			dst.SetMin(c.GetMin())
+			dst.SetPolicy(c.GetPolicy())
 			ok = true
 			return
 		}
 	}
 }
+
+// tryAcquire adds n if the result is not greater than max.
+func (c *MaxCounter) tryAcquire(n float64) bool {
+	for {
+		max := c.GetMax()
+		oldBits := atomic.LoadUint64(&c.counter.bits)
+		newVal := math.Float64frombits(oldBits) + n
+		if newVal > max {
+			return false
+		}
+
+		newBits := math.Float64bits(newVal)
+		if atomic.CompareAndSwapUint64(&c.counter.bits, oldBits, newBits) {
+			c.counter.changed(newVal)
+			return true
+		}
+	}
+}
+
+// notifyWaiters wakes the waiters of Acquire in FIFO order,
+// while there is room for them.
+// Nobody waiting, it does not take the lock.
+func (c *MaxCounter) notifyWaiters() {
+	if atomic.LoadInt32(&c.waiting) == 0 {
+		return
+	}
+
+	c.waitMux.Lock()
+	defer c.waitMux.Unlock()
+
+	c.notifyWaitersLocked()
+}
+
+// notifyWaitersLocked is notifyWaiters, waitMux must be held.
+func (c *MaxCounter) notifyWaitersLocked() {
+	for {
+		front := c.waiters.Front()
+		if front == nil {
+			return
+		}
+
+		w := front.Value.(*maxWaiter)
+		if !c.tryAcquire(w.n) {
+			// keep FIFO, the others wait behind the first one
+			return
+		}
+
+		c.waiters.Remove(front)
+		atomic.AddInt32(&c.waiting, -1)
+		close(w.ready)
+	}
+}
+
+// Acquire adds n to the counter, blocking until the result is not greater than max,
+// or the context is done. So MaxCounter can be used as a semaphore.
+// Waiters are woken in FIFO order when Dec, Sub, Release, Set or SetMax frees room.
+//
+// Acquire does not follow the OverflowPolicy,
+// and Inc or Add do not wait in the queue, do not mix them with Acquire.
+func (c *MaxCounter) Acquire(ctx context.Context, n float64) error {
+	c.waitMux.Lock()
+	// count before trying, so a concurrent release can not miss us
+	atomic.AddInt32(&c.waiting, 1)
+	if c.waiters.Len() == 0 && c.tryAcquire(n) {
+		atomic.AddInt32(&c.waiting, -1)
+		c.waitMux.Unlock()
+		return nil
+	}
+
+	w := &maxWaiter{n: n, ready: make(chan struct{})}
+	elem := c.waiters.PushBack(w)
+	c.waitMux.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	c.waitMux.Lock()
+	defer c.waitMux.Unlock()
+
+	select {
+	case <-w.ready:
+		// acquired after cancel, keep it
+		return nil
+	default:
+	}
+
+	isFront := c.waiters.Front() == elem
+	c.waiters.Remove(elem)
+	atomic.AddInt32(&c.waiting, -1)
+
+	// the first one is gone, the next ones may fit now
+	if isFront {
+		c.notifyWaitersLocked()
+	}
+
+	return ctx.Err()
+}
+
+// TryAcquire adds n to the counter without blocking.
+// It returns false if the result would be greater than max,
+// or other goroutines are waiting in Acquire.
+func (c *MaxCounter) TryAcquire(n float64) bool {
+	if atomic.LoadInt32(&c.waiting) != 0 {
+		return false
+	}
+
+	return c.tryAcquire(n)
+}
+
+// Release subtracts n from the counter and wakes the waiters of Acquire.
+func (c *MaxCounter) Release(n float64) bool {
+	return c.Sub(n)
+}
@@ -1,10 +1,12 @@
 package gounter
 
 import (
+	"context"
 	"math/rand"
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestMaxCounterReleaseNil(t *testing.T) {
@@ -290,3 +292,394 @@ func testMaxCounterCopyTo(t *testing.T) {
 		ReleaseMaxCounter(c1)
 	}
 }
+
+func TestMaxCounterNoOvershoot(t *testing.T) {
+	t.Parallel()
+
+	c := AcquireMaxCounter(100)
+	defer ReleaseMaxCounter(c)
+
+	wg := sync.WaitGroup{}
+	wg.Add(1000)
+	for i := 0; i < 1000; i++ {
+		go func() {
+			c.Add(3)
+			wg.Done()
+		}()
+	}
+
+	wg.Wait()
+
+	if v := c.Get(); v != 99 {
+		t.Fatalf("should be %d, but %f", 99, v)
+	}
+	if !c.Can() {
+		t.Fatal("should not be done, but done")
+	}
+
+	if ok := c.Inc(); !ok {
+		t.Fatal("should be true, but false")
+	}
+	if ok := c.Inc(); ok {
+		t.Fatal("should be false, but true")
+	}
+	if c.Can() {
+		t.Fatal("should be done, but not")
+	}
+}
+
+func TestMaxCounterRejectLarge(t *testing.T) {
+	t.Parallel()
+
+	c := AcquireMaxCounter(10)
+	defer ReleaseMaxCounter(c)
+
+	if ok := c.Add(11); ok {
+		t.Fatal("should be false, but true")
+	}
+	if ok := c.Inc(); !ok {
+		t.Fatal("should be true, but false")
+	}
+	if v := c.Real(); v != 1 {
+		t.Fatalf("should be %d, but %f", 1, v)
+	}
+}
+
+func TestMaxCounterRejectLargeSub(t *testing.T) {
+	t.Parallel()
+
+	c := AcquireMaxCounter(10)
+	defer ReleaseMaxCounter(c)
+	c.Add(5)
+
+	if ok := c.Sub(10); ok {
+		t.Fatal("should be false, but true")
+	}
+	if ok := c.Sub(1); !ok {
+		t.Fatal("should be true, but false")
+	}
+	if v := c.Real(); v != 4 {
+		t.Fatalf("should be %d, but %f", 4, v)
+	}
+}
+
+func TestMaxCounterOutOfBounds(t *testing.T) {
+	t.Parallel()
+
+	for _, policy := range []OverflowPolicy{OverflowReject, OverflowClamp} {
+		c := AcquireRangeCounter(-10, 10)
+		c.SetPolicy(policy)
+
+		// above max after lowering it
+		c.Set(8)
+		c.SetMax(5)
+		if ok := c.Sub(1); !ok || c.Real() != 7 {
+			t.Errorf("%d, should be 7, but %f", policy, c.Real())
+		}
+		if ok := c.Inc(); ok {
+			t.Errorf("%d, should be false, but true", policy)
+		}
+
+		// below min after raising it
+		c.SetMax(10)
+		c.Set(-8)
+		c.SetMin(-5)
+		if ok := c.Inc(); !ok || c.Real() != -7 {
+			t.Errorf("%d, should be -7, but %f", policy, c.Real())
+		}
+		if ok := c.Dec(); ok {
+			t.Errorf("%d, should be false, but true", policy)
+		}
+
+		ReleaseMaxCounter(c)
+	}
+}
+
+func TestMaxCounterPolicy(t *testing.T) {
+	t.Parallel()
+
+	c := AcquireMaxCounter(10)
+	defer ReleaseMaxCounter(c)
+
+	// reject
+	c.Add(8)
+	if applied, ok := c.AddApplied(5); ok || applied != 0 {
+		t.Fatalf("should be rejected, but applied %f", applied)
+	}
+	if v := c.Get(); v != 8 {
+		t.Fatalf("should be %d, but %f", 8, v)
+	}
+	if ok := c.Sub(9); ok {
+		t.Fatal("should be false, but true")
+	}
+
+	// a rejected delta does not block a smaller one
+	if !c.Can() {
+		t.Fatal("should not be done, but done")
+	}
+	if ok := c.Add(2); !ok {
+		t.Fatal("should be true, but false")
+	}
+	if v := c.Get(); v != 10 {
+		t.Fatalf("should be %d, but %f", 10, v)
+	}
+
+	// clamp
+	c.Reset()
+	c.SetMax(10)
+	c.SetPolicy(OverflowClamp)
+	if c.GetPolicy() != OverflowClamp {
+		t.Fatalf("policy should be %d, but %d", OverflowClamp, c.GetPolicy())
+	}
+	c.Add(8)
+	if applied, ok := c.AddApplied(5); !ok || applied != 2 {
+		t.Fatalf("should apply %d, but %f", 2, applied)
+	}
+	if ok := c.Inc(); ok {
+		t.Fatal("should be false, but true")
+	}
+	if c.Can() {
+		t.Fatal("should be done, but not")
+	}
+	if applied, ok := c.AddApplied(-15); !ok || applied != -10 {
+		t.Fatalf("should apply %d, but %f", -10, applied)
+	}
+	if ok := c.Dec(); ok {
+		t.Fatal("should be false, but true")
+	}
+
+	// allow and flag
+	c.SetPolicy(OverflowAllow)
+	if applied, ok := c.AddApplied(15); !ok || applied != 15 {
+		t.Fatalf("should apply %d, but %f", 15, applied)
+	}
+	if c.Can() {
+		t.Fatal("should be flagged, but not")
+	}
+	c.Sub(6)
+	if !c.Can() {
+		t.Fatal("should not be flagged, but flagged")
+	}
+	if v := c.Get(); v != 9 {
+		t.Fatalf("should be %d, but %f", 9, v)
+	}
+
+	// copy policy
+	c2 := AcquireMaxCounter(1)
+	defer ReleaseMaxCounter(c2)
+	if c2.GetPolicy() != OverflowReject {
+		t.Fatalf("policy should be %d, but %d", OverflowReject, c2.GetPolicy())
+	}
+	c.CopyTo(c2)
+	if c2.GetPolicy() != OverflowAllow {
+		t.Fatalf("policy should be %d, but %d", OverflowAllow, c2.GetPolicy())
+	}
+}
+
+func TestMaxCounterRange(t *testing.T) {
+	t.Parallel()
+
+	c := AcquireRangeCounter(-100, 500)
+	defer ReleaseMaxCounter(c)
+
+	if c.GetMin() != -100 {
+		t.Fatalf("min should be %d, but %f", -100, c.GetMin())
+	}
+
+	c.Sub(60)
+	if v := c.Get(); v != -60 {
+		t.Fatalf("should be %d, but %f", -60, v)
+	}
+
+	if ok := c.Sub(50); ok {
+		t.Fatal("should be false, but true")
+	}
+	if !c.CanSub() {
+		t.Fatal("should not be min done, but done")
+	}
+	if ok := c.Sub(1); !ok {
+		t.Fatal("should be true, but false")
+	}
+
+	// at min
+	c.Sub(39)
+	if ok := c.Dec(); ok {
+		t.Fatal("should be false, but true")
+	}
+	if c.CanSub() {
+		t.Fatal("should be min done, but not")
+	}
+
+	// undone
+	c.Inc()
+	if !c.CanSub() {
+		t.Fatal("should not be min done, but done")
+	}
+	if v := c.Get(); v != -99 {
+		t.Fatalf("should be %d, but %f", -99, v)
+	}
+
+	if ok := c.Set(-101); ok {
+		t.Fatal("should be false, but true")
+	}
+
+	// clamp
+	c.SetPolicy(OverflowClamp)
+	if applied, ok := c.AddApplied(-100); !ok || applied != -1 {
+		t.Fatalf("should apply %d, but %f", -1, applied)
+	}
+	if v := c.Get(); v != -100 {
+		t.Fatalf("should be %d, but %f", -100, v)
+	}
+}
+
+func TestLabelCounterWithRange(t *testing.T) {
+	t.Parallel()
+
+	c := NewLabelCounterWithRange(-10, 10)
+
+	c.Add("a", 0)
+	for i := 0; i < 20; i++ {
+		c.Dec("a")
+	}
+	if v, _ := c.Get("a"); v != -10 {
+		t.Fatalf("should be %d, but %f", -10, v)
+	}
+}
+
+func TestMaxCounterAcquire(t *testing.T) {
+	t.Parallel()
+
+	c := AcquireMaxCounter(2)
+	defer ReleaseMaxCounter(c)
+
+	ctx := context.Background()
+	if err := c.Acquire(ctx, 2); err != nil {
+		t.Fatalf("should acquire, but %v", err)
+	}
+	if c.TryAcquire(1) {
+		t.Fatal("should be false, but true")
+	}
+
+	// FIFO
+	order := make(chan int, 3)
+	for i := 0; i < 3; i++ {
+		go func(i int) {
+			if err := c.Acquire(ctx, 1); err != nil {
+				t.Errorf("should acquire, but %v", err)
+			}
+			order <- i
+		}(i)
+
+		// make sure the waiters are queued in order
+		for atomic.LoadInt32(&c.waiting) != int32(i+1) {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		c.Release(1)
+
+		select {
+		case got := <-order:
+			if got != i {
+				t.Fatalf("should wake %d, but %d", i, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	}
+
+	if v := c.Get(); v != 2 {
+		t.Fatalf("should be %d, but %f", 2, v)
+	}
+}
+
+func TestMaxCounterAcquireCancel(t *testing.T) {
+	t.Parallel()
+
+	c := AcquireMaxCounter(1)
+	defer ReleaseMaxCounter(c)
+
+	if !c.TryAcquire(1) {
+		t.Fatal("should be true, but false")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := c.Acquire(ctx, 1); err != context.DeadlineExceeded {
+		t.Fatalf("should be %v, but %v", context.DeadlineExceeded, err)
+	}
+	if n := atomic.LoadInt32(&c.waiting); n != 0 {
+		t.Fatalf("waiting should be %d, but %d", 0, n)
+	}
+
+	// SetMax frees room
+	done := make(chan error)
+	go func() {
+		done <- c.Acquire(context.Background(), 1)
+	}()
+
+	for atomic.LoadInt32(&c.waiting) != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	c.SetMax(2)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("should acquire, but %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestLabelCounterWithMaxFunc(t *testing.T) {
+	t.Parallel()
+
+	quotas := map[string]float64{"small": 2, "large": 5}
+	c := NewLabelCounterWithMaxFunc(func(label string) float64 {
+		return quotas[label]
+	})
+
+	for i := 0; i < 10; i++ {
+		c.Inc("small")
+		c.Inc("large")
+	}
+
+	if v, _ := c.Get("small"); v != 2 {
+		t.Fatalf("should be %d, but %f", 2, v)
+	}
+	if v, _ := c.Get("large"); v != 5 {
+		t.Fatalf("should be %d, but %f", 5, v)
+	}
+
+	if ok := SetLabelMax(c, "small", 3); !ok {
+		t.Fatal("should be true, but false")
+	}
+	c.Inc("small")
+	c.Inc("small")
+	if v, _ := c.Get("small"); v != 3 {
+		t.Fatalf("should be %d, but %f", 3, v)
+	}
+
+	// not exist
+	if ok := SetLabelMax(c, "new", 1); !ok {
+		t.Fatal("should be true, but false")
+	}
+	if _, m := c.Get("new"); m.GetMax() != 1 {
+		t.Fatalf("should be %d, but %f", 1, m.GetMax())
+	}
+
+	// folded into the overflow label
+	c.SetLabelLimit(3, LabelLimitOverflow)
+	c.Inc(OverflowLabel)
+	if ok := SetLabelMax(c, "unknown", 100); ok {
+		t.Fatal("should be false, but true")
+	}
+	if _, m := c.Get(OverflowLabel); m.GetMax() != 0 {
+		t.Fatalf("should be %d, but %f", 0, m.GetMax())
+	}
+}
@@ -0,0 +1,175 @@
+package gounter
+
+import "sync"
+
+// Observer reacts to the changes of counters.
+// Embed NopObserver to implement only the needed methods.
+//
+// Observers are called synchronously, in the goroutine making the change,
+// do not call back into the observed counter.
+type Observer interface {
+	// OnChange is called when the value of the counter changed.
+	OnChange(old, new float64)
+	// OnLimit is called when a change sets the done flag of a MaxCounter,
+	// at its max or its min. It is called once until the flag is cleared,
+	// not for every rejected change.
+	OnLimit(value float64)
+	// OnLabelCreated is called when a LabelCounter creates a label.
+	OnLabelCreated(label string)
+	// OnLabelRemoved is called when a LabelCounter removes a label.
+	OnLabelRemoved(label string)
+}
+
+// NopObserver is an Observer does nothing.
+type NopObserver struct{}
+
+// OnChange does nothing.
+func (NopObserver) OnChange(old, new float64) {}
+
+// OnLimit does nothing.
+func (NopObserver) OnLimit(value float64) {}
+
+// OnLabelCreated does nothing.
+func (NopObserver) OnLabelCreated(label string) {}
+
+// OnLabelRemoved does nothing.
+func (NopObserver) OnLabelRemoved(label string) {}
+
+// ObservedGounter wraps a Gounter and notifies observers on mutations.
+// The old value is read before the mutation,
+// under concurrent mutations it may not be the exact previous value.
+type ObservedGounter struct {
+	noCopy noCopy
+
+	counter   Gounter
+	observers []Observer
+	mux       sync.RWMutex
+}
+
+// Observe wraps the Gounter with observers.
+func Observe(c Gounter, observers ...Observer) *ObservedGounter {
+	return &ObservedGounter{
+		counter:   c,
+		observers: observers,
+	}
+}
+
+// AddObserver adds an observer.
+func (o *ObservedGounter) AddObserver(observer Observer) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	o.observers = append(o.observers, observer)
+}
+
+// Unwrap returns the wrapped Gounter.
+func (o *ObservedGounter) Unwrap() Gounter {
+	return o.counter
+}
+
+// Real returns Real() of the wrapped Gounter if it has it, else Get().
+func (o *ObservedGounter) Real() float64 {
+	return realOf(o.counter)
+}
+
+// limiter is implemented by MaxCounter.
+type limiter interface {
+	Can() bool
+	CanSub() bool
+}
+
+// observe runs the mutation and notifies observers.
+func (o *ObservedGounter) observe(f func() bool) bool {
+	limit, isLimiter := o.counter.(limiter)
+	var can, canSub bool
+	if isLimiter {
+		can, canSub = limit.Can(), limit.CanSub()
+	}
+
+	old := o.Real()
+	ok := f()
+	val := o.Real()
+
+	o.mux.RLock()
+	defer o.mux.RUnlock()
+
+	if ok && old != val {
+		for _, observer := range o.observers {
+			observer.OnChange(old, val)
+		}
+	}
+
+	// the done flag is set by this change
+	if isLimiter && (can && !limit.Can() || canSub && !limit.CanSub()) {
+		for _, observer := range o.observers {
+			observer.OnLimit(val)
+		}
+	}
+
+	return ok
+}
+
+// Get returns the value of the wrapped Gounter.
+func (o *ObservedGounter) Get() float64 {
+	return o.counter.Get()
+}
+
+// Reset resets the wrapped Gounter.
+func (o *ObservedGounter) Reset() {
+	o.observe(func() bool {
+		o.counter.Reset()
+		return true
+	})
+}
+
+// Set sets the wrapped Gounter.
+func (o *ObservedGounter) Set(value float64) bool {
+	return o.observe(func() bool {
+		return o.counter.Set(value)
+	})
+}
+
+// Add adds to the wrapped Gounter.
+func (o *ObservedGounter) Add(delta float64) bool {
+	return o.observe(func() bool {
+		return o.counter.Add(delta)
+	})
+}
+
+// Sub subtracts from the wrapped Gounter.
+func (o *ObservedGounter) Sub(delta float64) bool {
+	return o.observe(func() bool {
+		return o.counter.Sub(delta)
+	})
+}
+
+// Inc increases the wrapped Gounter by 1.
+func (o *ObservedGounter) Inc() bool {
+	return o.observe(o.counter.Inc)
+}
+
+// Dec decreases the wrapped Gounter by 1.
+func (o *ObservedGounter) Dec() bool {
+	return o.observe(o.counter.Dec)
+}
+
+// CopyTo copies the wrapped Gounter to the Gounter wrapped by other ObservedGounter.
+// The observers of dst are notified.
+func (o *ObservedGounter) CopyTo(d interface{}) (ok bool, err error) {
+	dst, can := d.(*ObservedGounter)
+	if !can {
+		err = ErrDifferentCounterType
+		return
+	}
+
+	if o == dst {
+		err = ErrSameCounterPointer
+		return
+	}
+
+	dst.observe(func() bool {
+		ok, err = o.counter.CopyTo(dst.counter)
+		return ok
+	})
+	return
+}
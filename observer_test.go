@@ -0,0 +1,141 @@
+package gounter
+
+import (
+	"sync"
+	"testing"
+)
+
+// testObserver records the calls.
+type testObserver struct {
+	NopObserver
+
+	mux     sync.Mutex
+	changes [][2]float64
+	limits  []float64
+	created []string
+	removed []string
+}
+
+func (o *testObserver) OnChange(old, new float64) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	o.changes = append(o.changes, [2]float64{old, new})
+}
+
+func (o *testObserver) OnLimit(value float64) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	o.limits = append(o.limits, value)
+}
+
+func (o *testObserver) OnLabelCreated(label string) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	o.created = append(o.created, label)
+}
+
+func (o *testObserver) OnLabelRemoved(label string) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	o.removed = append(o.removed, label)
+}
+
+func TestObserveCounter(t *testing.T) {
+	t.Parallel()
+
+	o := &testObserver{}
+	c := Observe(AcquireCounter(), o)
+	defer ReleaseCounter(c.Unwrap().(*Counter))
+
+	c.Inc()
+	c.Add(2)
+	c.Add(0)
+	c.Dec()
+
+	want := [][2]float64{{0, 1}, {1, 3}, {3, 2}}
+	if len(o.changes) != len(want) {
+		t.Fatalf("changes should be %v, but %v", want, o.changes)
+	}
+	for i := range want {
+		if o.changes[i] != want[i] {
+			t.Fatalf("changes should be %v, but %v", want, o.changes)
+		}
+	}
+
+	// copy notifies dst
+	o2 := &testObserver{}
+	c2 := Observe(AcquireCounter(), o2)
+	defer ReleaseCounter(c2.Unwrap().(*Counter))
+
+	ok, err := c.CopyTo(c2)
+	if !ok || err != nil {
+		t.Fatalf("counter should be copied, but err: %v", err)
+	}
+	if len(o2.changes) != 1 || o2.changes[0] != [2]float64{0, 2} {
+		t.Fatalf("changes should be %v, but %v", [2]float64{0, 2}, o2.changes)
+	}
+}
+
+func TestObserveMaxCounter(t *testing.T) {
+	t.Parallel()
+
+	o := &testObserver{}
+	c := Observe(AcquireMaxCounter(2))
+	c.AddObserver(o)
+	defer ReleaseMaxCounter(c.Unwrap().(*MaxCounter))
+
+	c.Inc()
+	c.Inc()
+	if ok := c.Inc(); ok {
+		t.Fatal("should be false, but true")
+	}
+
+	if ok := c.Inc(); ok {
+		t.Fatal("should be false, but true")
+	}
+
+	// once until the flag is cleared
+	if len(o.limits) != 1 || o.limits[0] != 2 {
+		t.Fatalf("limits should be %v, but %v", []float64{2}, o.limits)
+	}
+
+	// min edge
+	o = &testObserver{}
+	r := Observe(AcquireRangeCounter(-1, 1), o)
+	defer ReleaseMaxCounter(r.Unwrap().(*MaxCounter))
+
+	r.Dec()
+	for i := 0; i < 4; i++ {
+		if ok := r.Dec(); ok {
+			t.Fatal("should be false, but true")
+		}
+	}
+	r.Inc()
+	r.Dec()
+	r.Dec()
+
+	if len(o.limits) != 2 || o.limits[0] != -1 || o.limits[1] != -1 {
+		t.Fatalf("limits should be %v, but %v", []float64{-1, -1}, o.limits)
+	}
+}
+
+func TestObserveLabelCounter(t *testing.T) {
+	t.Parallel()
+
+	o := &testObserver{}
+	c := NewLabelCounterNormal()
+	c.Observe(o)
+
+	c.Inc("a")
+	c.Inc("a")
+	c.Inc("b")
+	c.RemoveLabel("a")
+	c.Reset()
+
+	if len(o.created) != 2 || o.created[0] != "a" || o.created[1] != "b" {
+		t.Fatalf("created should be %v, but %v", []string{"a", "b"}, o.created)
+	}
+	if len(o.removed) != 2 || o.removed[0] != "a" || o.removed[1] != "b" {
+		t.Fatalf("removed should be %v, but %v", []string{"a", "b"}, o.removed)
+	}
+}
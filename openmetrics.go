@@ -0,0 +1,163 @@
+package gounter
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenMetricsContentType is the content type of the OpenMetrics text format.
+const OpenMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// formatTimestamp formats the time as seconds since epoch for OpenMetrics.
+func formatTimestamp(t time.Time) string {
+	sec := strconv.FormatInt(t.Unix(), 10)
+	nsec := t.Nanosecond()
+	if nsec == 0 {
+		return sec
+	}
+
+	frac := strconv.Itoa(nsec + 1e9)[1:]
+	return sec + "." + strings.TrimRight(frac, "0")
+}
+
+// writeExemplar writes ` # {labels} value timestamp`.
+func writeExemplar(w *bufio.Writer, e *Exemplar) {
+	names := make([]string, 0, len(e.Labels))
+	for name := range e.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w.WriteString(" # {")
+	for i, name := range names {
+		if i > 0 {
+			w.WriteString(",")
+		}
+		w.WriteString(name)
+		w.WriteString(`="`)
+		labelEscaper.WriteString(w, e.Labels[name])
+		w.WriteString(`"`)
+	}
+	w.WriteString("} ")
+	w.WriteString(formatFloat(e.Value))
+	w.WriteString(" ")
+	w.WriteString(formatTimestamp(e.Timestamp))
+}
+
+// writeOpenMetricsSeries writes a line of `name{label="value"} v`,
+// with the exemplar if it is not nil.
+func writeOpenMetricsSeries(w *bufio.Writer, name string, desc MetricDesc, s sample, v string, e *Exemplar) {
+	w.WriteString(name)
+	if s.labelled {
+		w.WriteString("{")
+		w.WriteString(desc.LabelName)
+		w.WriteString(`="`)
+		labelEscaper.WriteString(w, s.label)
+		w.WriteString(`"}`)
+	}
+	w.WriteString(" ")
+	w.WriteString(v)
+	if e != nil {
+		writeExemplar(w, e)
+	}
+	w.WriteString("\n")
+}
+
+// writeOpenMetricsHeader writes the TYPE, UNIT and HELP lines.
+func writeOpenMetricsHeader(w *bufio.Writer, family, help, unit string, typ MetricType) {
+	w.WriteString("# TYPE ")
+	w.WriteString(family)
+	w.WriteString(" ")
+	w.WriteString(string(typ))
+	w.WriteString("\n")
+
+	if unit != "" {
+		w.WriteString("# UNIT ")
+		w.WriteString(family)
+		w.WriteString(" ")
+		w.WriteString(unit)
+		w.WriteString("\n")
+	}
+
+	if help != "" {
+		w.WriteString("# HELP ")
+		w.WriteString(family)
+		w.WriteString(" ")
+		labelEscaper.WriteString(w, help)
+		w.WriteString("\n")
+	}
+}
+
+// labelCreator is implemented by LabelCounter and ShardedLabelCounter.
+type labelCreator interface {
+	labelCreated(label string) (time.Time, bool)
+}
+
+// WriteOpenMetrics writes all registered metrics in the OpenMetrics text format,
+// sorted by name and terminated by `# EOF`.
+//
+// Counters are written as `<family>_total` with `<family>_created`,
+// the created timestamp is the time of registration,
+// or the time the label is created for labelled series.
+// `_created` is omitted if the label is removed while writing.
+// The exemplar of the last Counter.AddWithExemplar is attached to `_total`.
+// The max of MaxCounter is written as a companion gauge `<family>_max`.
+func (r *Registry) WriteOpenMetrics(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	for _, m := range r.sortedMetrics() {
+		family := m.desc.family()
+		samples := m.samples()
+
+		writeOpenMetricsHeader(bw, family, m.desc.Help, m.desc.Unit, m.desc.Type)
+		for _, s := range samples {
+			if m.desc.Type != MetricCounter {
+				writeOpenMetricsSeries(bw, family, m.desc, s, formatFloat(s.value), nil)
+				continue
+			}
+
+			var e *Exemplar
+			if c, ok := s.counter.(*Counter); ok {
+				e = c.Exemplar()
+			}
+
+			writeOpenMetricsSeries(bw, family+"_total", m.desc, s, formatFloat(s.value), e)
+
+			created := m.created
+			if s.labelled {
+				var ok bool
+				if creator, can := m.value.(labelCreator); can {
+					created, ok = creator.labelCreated(s.label)
+				}
+				if !ok {
+					continue
+				}
+			}
+			writeOpenMetricsSeries(bw, family+"_created", m.desc, s, formatTimestamp(created), nil)
+		}
+
+		// companion gauge of MaxCounter
+		maxFamily := family + "_max"
+		header := false
+		for _, s := range samples {
+			max, ok := s.max()
+			if !ok {
+				continue
+			}
+
+			if !header {
+				writeOpenMetricsHeader(bw, maxFamily, "Max of "+family+".", "", MetricGauge)
+				header = true
+			}
+			writeOpenMetricsSeries(bw, maxFamily, m.desc, s, formatFloat(max), nil)
+		}
+	}
+
+	bw.WriteString("# EOF\n")
+
+	return bw.Flush()
+}
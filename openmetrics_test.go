@@ -0,0 +1,437 @@
+package gounter
+
+import (
+	"math"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestFormatTimestamp(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]time.Time{
+		"1520879607":           time.Unix(1520879607, 0),
+		"1520879607.789":       time.Unix(1520879607, 789000000),
+		"1520879607.000000001": time.Unix(1520879607, 1),
+		"0":                    time.Unix(0, 0),
+	}
+	for want, ts := range tests {
+		if got := formatTimestamp(ts); got != want {
+			t.Errorf("should be %s, but %s", want, got)
+		}
+	}
+}
+
+func TestCounterAddWithExemplar(t *testing.T) {
+	t.Parallel()
+
+	c := AcquireCounter()
+	defer ReleaseCounter(c)
+
+	if e := c.Exemplar(); e != nil {
+		t.Fatalf("should be nil, but %v", e)
+	}
+
+	c.AddWithExemplar(0.67, map[string]string{"trace_id": "KOO5S4vxi0o"})
+	e := c.Exemplar()
+	if e == nil || e.Value != 0.67 || e.Labels["trace_id"] != "KOO5S4vxi0o" {
+		t.Fatalf("wrong exemplar, got %v", e)
+	}
+
+	// too long, dropped but added
+	c.AddWithExemplar(1, map[string]string{"trace_id": strings.Repeat("x", 121)})
+	if c.Exemplar() != e {
+		t.Fatal("exemplar should be kept")
+	}
+	if v := c.Get(); v != 1.67 {
+		t.Fatalf("should be %f, but %f", 1.67, v)
+	}
+
+	c.Reset()
+	if e := c.Exemplar(); e != nil {
+		t.Fatalf("should be nil, but %v", e)
+	}
+}
+
+func TestRegistryWriteOpenMetrics(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+
+	// the counter example of the spec
+	foo := AcquireCounter()
+	defer ReleaseCounter(foo)
+	foo.Add(17)
+	foo.exemplar.Store(&Exemplar{
+		Labels:    map[string]string{"id": "counter-test"},
+		Value:     5,
+		Timestamp: time.Unix(1520879607, 789000000),
+	})
+
+	now := time.Unix(1520430001, 500000000)
+	latency := NewLabelCounterNormal()
+	latency.SetClock(func() time.Time { return now })
+	latency.Add("/api", 1.5)
+	now = now.Add(750 * time.Millisecond)
+	latency.Add(`say "hi"`, 0.25)
+
+	conns := AcquireMaxCounter(100)
+	defer ReleaseMaxCounter(conns)
+	conns.Add(3)
+
+	for _, reg := range []struct {
+		desc MetricDesc
+		c    any
+	}{
+		{MetricDesc{Name: "foo_total"}, foo},
+		{MetricDesc{Name: "http_latency_seconds_total", Help: "Latency \"sum\".", LabelName: "path", Unit: "seconds"}, latency},
+		{MetricDesc{Name: "connections", Help: "Open connections.", Type: MetricGauge}, conns},
+	} {
+		if err := r.Register(reg.desc, reg.c); err != nil {
+			t.Fatalf("should be nil, but %v", err)
+		}
+		r.metrics[reg.desc.Name].created = time.Unix(1520430000, 123000000)
+	}
+
+	out := &strings.Builder{}
+	if err := r.WriteOpenMetrics(out); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+
+	want := `# TYPE connections gauge
+# HELP connections Open connections.
+connections 3
+# TYPE connections_max gauge
+# HELP connections_max Max of connections.
+connections_max 100
+# TYPE foo counter
+foo_total 17 # {id="counter-test"} 5 1520879607.789
+foo_created 1520430000.123
+# TYPE http_latency_seconds counter
+# UNIT http_latency_seconds seconds
+# HELP http_latency_seconds Latency \"sum\".
+http_latency_seconds_total{path="/api"} 1.5
+http_latency_seconds_created{path="/api"} 1520430001.5
+http_latency_seconds_total{path="say \"hi\""} 0.25
+http_latency_seconds_created{path="say \"hi\""} 1520430002.25
+# EOF
+`
+	if got := out.String(); got != want {
+		t.Fatalf("wrong result, expect\n%s\ngot\n%s", want, got)
+	}
+
+	testCheckOpenMetrics(t, out.String())
+}
+
+func TestRegistryOpenMetricsLabelCreated(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+
+	now := time.Unix(1520430000, 0)
+	c := NewLabelCounterNormal()
+	c.SetClock(func() time.Time { return now })
+	c.Inc("a")
+	if err := r.Register(MetricDesc{Name: "hits_total"}, c); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+
+	// labels created after reset have a new created time
+	now = now.Add(time.Minute)
+	c.Reset()
+	c.Inc("a")
+
+	out := &strings.Builder{}
+	if err := r.WriteOpenMetrics(out); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+
+	want := `# TYPE hits counter
+hits_total{label="a"} 1
+hits_created{label="a"} 1520430060
+# EOF
+`
+	if got := out.String(); got != want {
+		t.Fatalf("wrong result, expect\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestRegistryOpenMetricsConformance(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+
+	c := AcquireCounter()
+	defer ReleaseCounter(c)
+	c.AddWithExemplar(1, map[string]string{"trace_id": "abc", "span_id": "de\"f"})
+
+	labels := NewLabelCounterWithMax(10)
+	labels.Add("a\\b\nc", 2)
+	labels.Set("inf", 10)
+
+	weird := NewLabelCounterNormal()
+	weird.Set("nan", math.NaN())
+	weird.Set("neg", math.Inf(-1))
+
+	empty := NewLabelCounterNormal()
+
+	r.Register(MetricDesc{Name: "requests_total", Help: "multi\nline \\ help"}, c)
+	r.Register(MetricDesc{Name: "limits", Type: MetricGauge, LabelName: "key"}, labels)
+	r.Register(MetricDesc{Name: "weird", Type: MetricGauge}, weird)
+	r.Register(MetricDesc{Name: "empty_bytes_total", Unit: "bytes"}, empty)
+	r.Register(MetricDesc{Name: "no_suffix"}, AcquireShardedCounter())
+
+	out := &strings.Builder{}
+	if err := r.WriteOpenMetrics(out); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+
+	testCheckOpenMetrics(t, out.String())
+}
+
+func TestRegistryRegisterUnit(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+	c := AcquireCounter()
+	defer ReleaseCounter(c)
+
+	tests := []struct {
+		desc MetricDesc
+		err  error
+	}{
+		{MetricDesc{Name: "a_seconds_total", Unit: "seconds"}, nil},
+		{MetricDesc{Name: "b_seconds", Unit: "seconds", Type: MetricGauge}, nil},
+		{MetricDesc{Name: "c_total", Unit: "seconds"}, ErrInvalidMetricUnit},
+		{MetricDesc{Name: "d_seconds", Unit: "sec onds"}, ErrInvalidMetricUnit},
+		{MetricDesc{Name: "e_seconds_total", Unit: "seconds", Type: MetricGauge}, ErrInvalidMetricUnit},
+	}
+	for _, tt := range tests {
+		if err := r.Register(tt.desc, c); err != tt.err {
+			t.Errorf("%s, should be %v, but %v", tt.desc.Name, tt.err, err)
+		}
+	}
+}
+
+func TestRegistryHandlerOpenMetrics(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+	c := AcquireCounter()
+	defer ReleaseCounter(c)
+	r.Register(MetricDesc{Name: "x_total"}, c)
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0,text/plain;q=0.5")
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != OpenMetricsContentType {
+		t.Errorf("should be %s, but %s", OpenMetricsContentType, ct)
+	}
+	testCheckOpenMetrics(t, rec.Body.String())
+}
+
+// testCheckOpenMetrics checks the rules of the OpenMetrics text format
+// for the families gounter writes.
+func testCheckOpenMetrics(t *testing.T, text string) {
+	t.Helper()
+
+	if !strings.HasSuffix(text, "# EOF\n") {
+		t.Fatalf("should end with # EOF, got\n%s", text)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(text, "# EOF\n"), "\n")
+	lines = lines[:len(lines)-1]
+
+	families := make(map[string]bool)
+	var family, typ string
+	var meta map[string]bool
+	samples := false
+
+	for _, line := range lines {
+		if line == "" {
+			t.Fatalf("empty line in\n%s", text)
+		}
+
+		if strings.HasPrefix(line, "# ") {
+			parts := strings.SplitN(line, " ", 4)
+			if len(parts) < 4 {
+				t.Fatalf("bad metadata %q", line)
+			}
+
+			kind, name, value := parts[1], parts[2], parts[3]
+			if kind == "EOF" {
+				t.Fatalf("# EOF in the middle of\n%s", text)
+			}
+
+			if name != family {
+				if kind != "TYPE" {
+					t.Fatalf("%s before TYPE: %q", kind, line)
+				}
+				if families[name] {
+					t.Fatalf("family %s is interleaved", name)
+				}
+
+				families[name] = true
+				family, meta, samples = name, make(map[string]bool), false
+			}
+
+			if samples {
+				t.Fatalf("metadata after samples: %q", line)
+			}
+			if meta[kind] {
+				t.Fatalf("duplicate %s: %q", kind, line)
+			}
+			meta[kind] = true
+
+			switch kind {
+			case "TYPE":
+				if value != "counter" && value != "gauge" {
+					t.Fatalf("bad type %q", line)
+				}
+				typ = value
+			case "UNIT":
+				if !strings.HasSuffix(family, "_"+value) {
+					t.Fatalf("family should have unit suffix: %q", line)
+				}
+			case "HELP":
+				testCheckEscaped(t, value, line)
+			default:
+				t.Fatalf("bad metadata %q", line)
+			}
+			continue
+		}
+
+		samples = true
+
+		// name
+		i := strings.IndexAny(line, "{ ")
+		if i < 0 {
+			t.Fatalf("bad sample %q", line)
+		}
+		name := line[:i]
+		rest := line[i:]
+
+		suffix := strings.TrimPrefix(name, family)
+		if !strings.HasPrefix(name, family) {
+			t.Fatalf("sample %q is not in family %s", line, family)
+		}
+		switch typ {
+		case "counter":
+			if suffix != "_total" && suffix != "_created" {
+				t.Fatalf("bad counter sample %q", line)
+			}
+		case "gauge":
+			if suffix != "" {
+				t.Fatalf("bad gauge sample %q", line)
+			}
+		}
+
+		// labels
+		if strings.HasPrefix(rest, "{") {
+			end := testLabelsEnd(rest)
+			if end < 0 {
+				t.Fatalf("bad labels %q", line)
+			}
+			rest = rest[end+1:]
+		}
+
+		// value
+		if !strings.HasPrefix(rest, " ") {
+			t.Fatalf("bad sample %q", line)
+		}
+		rest = rest[1:]
+		value := rest
+		exemplar := ""
+		if j := strings.Index(rest, " # "); j >= 0 {
+			value, exemplar = rest[:j], rest[j+3:]
+		}
+		testCheckFloat(t, value, line)
+
+		if exemplar == "" {
+			continue
+		}
+
+		if suffix != "_total" {
+			t.Fatalf("exemplar on %s: %q", suffix, line)
+		}
+
+		end := testLabelsEnd(exemplar)
+		if end < 0 {
+			t.Fatalf("bad exemplar %q", line)
+		}
+		runes := 0
+		for _, r := range exemplar[1:end] {
+			if r != '=' && r != ',' && r != '"' {
+				runes++
+			}
+		}
+		if runes > maxExemplarRunes {
+			t.Fatalf("exemplar too long %q", line)
+		}
+
+		fields := strings.Split(exemplar[end+1:], " ")
+		if len(fields) != 3 || fields[0] != "" {
+			t.Fatalf("bad exemplar %q", line)
+		}
+		testCheckFloat(t, fields[1], line)
+		testCheckFloat(t, fields[2], line)
+	}
+}
+
+// testLabelsEnd returns the index of the closing brace of `{a="b",...}`.
+func testLabelsEnd(s string) int {
+	quoted := false
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quoted && s[i] == '\\':
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == '\n':
+			return -1
+		case !quoted && s[i] == '}':
+			return i
+		}
+	}
+
+	return -1
+}
+
+// testCheckEscaped checks only \\, \n and \" are escaped.
+func testCheckEscaped(t *testing.T, s, line string) {
+	t.Helper()
+
+	if !utf8.ValidString(s) {
+		t.Fatalf("invalid utf-8 %q", line)
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			continue
+		}
+
+		i++
+		if i >= len(s) || (s[i] != '\\' && s[i] != 'n' && s[i] != '"') {
+			t.Fatalf("bad escape %q", line)
+		}
+	}
+}
+
+// testCheckFloat checks the number.
+func testCheckFloat(t *testing.T, s, line string) {
+	t.Helper()
+
+	switch s {
+	case "NaN", "+Inf", "-Inf":
+		return
+	}
+
+	if _, err := strconv.ParseFloat(s, 64); err != nil || strings.ContainsAny(s, "Ii") {
+		t.Fatalf("bad number %q in %q", s, line)
+	}
+}
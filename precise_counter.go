@@ -0,0 +1,149 @@
+package gounter
+
+import (
+	"math"
+	"sync"
+)
+
+// PreciseCounter is a Counter with compensated (Neumaier) summation.
+// It keeps a compensation term alongside the sum,
+// so adding many small fractional amounts does not drift.
+//
+// The sum and the compensation are updated together under a small mutex.
+// Like Counter, `Get()` returns 0 when the value is negative.
+//
+// Copying is prohibited. Please acquire new object.
+type PreciseCounter struct {
+	noCopy noCopy
+
+	sum  float64
+	comp float64
+	mux  sync.Mutex
+}
+
+// preciseCounterPool is a pool for PreciseCounter.
+var preciseCounterPool = &sync.Pool{
+	New: func() any {
+		return &PreciseCounter{}
+	},
+}
+
+// AcquirePreciseCounter return a PreciseCounter Pointer.
+func AcquirePreciseCounter() *PreciseCounter {
+	return preciseCounterPool.Get().(*PreciseCounter)
+}
+
+// ReleasePreciseCounter releases a PreciseCounter Pointer.
+func ReleasePreciseCounter(c *PreciseCounter) {
+	if c == nil {
+		return
+	}
+	c.reset()
+	preciseCounterPool.Put(c)
+}
+
+// reset PreciseCounter to release.
+func (c *PreciseCounter) reset() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.sum = 0
+	c.comp = 0
+}
+
+// Get returns the compensated total.
+// When the counter value is negative, it returns 0.
+func (c *PreciseCounter) Get() float64 {
+	val := c.Real()
+	if val < 0 {
+		return 0
+	}
+
+	return val
+}
+
+// Real returns the compensated total.
+func (c *PreciseCounter) Real() float64 {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return c.sum + c.comp
+}
+
+// Set sets the value of the counter and clears the compensation.
+// PreciseCounter always returns true.
+func (c *PreciseCounter) Set(value float64) bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.sum = value
+	c.comp = 0
+	return true
+}
+
+// Add increases the counter number.
+// Decreasing use negative number.
+// PreciseCounter always returns true.
+func (c *PreciseCounter) Add(delta float64) bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	t := c.sum + delta
+	if math.Abs(c.sum) >= math.Abs(delta) {
+		c.comp += (c.sum - t) + delta
+	} else {
+		c.comp += (delta - t) + c.sum
+	}
+	c.sum = t
+
+	return true
+}
+
+// Sub decreases the counter number.
+// PreciseCounter always returns true.
+func (c *PreciseCounter) Sub(delta float64) bool {
+	return c.Add(delta * -1)
+}
+
+// Inc increases the counter by 1.
+// PreciseCounter always returns true.
+func (c *PreciseCounter) Inc() bool {
+	return c.Add(1)
+}
+
+// Dec decreases the counter by 1.
+// PreciseCounter always returns true.
+func (c *PreciseCounter) Dec() bool {
+	return c.Add(-1)
+}
+
+// Reset resets this PreciseCounter.
+func (c *PreciseCounter) Reset() {
+	c.reset()
+}
+
+// CopyTo copies a PreciseCounter to other PreciseCounter,
+// including the compensation term.
+func (c *PreciseCounter) CopyTo(d interface{}) (ok bool, err error) {
+	dst, can := d.(*PreciseCounter)
+	if !can {
+		err = ErrDifferentCounterType
+		return
+	}
+
+	if c == dst {
+		err = ErrSameCounterPointer
+		return
+	}
+
+	c.mux.Lock()
+	sum, comp := c.sum, c.comp
+	c.mux.Unlock()
+
+	dst.mux.Lock()
+	dst.sum, dst.comp = sum, comp
+	dst.mux.Unlock()
+
+	ok = true
+	return
+}
@@ -0,0 +1,110 @@
+package gounter
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestPreciseCounterReleaseNil(t *testing.T) {
+	ReleasePreciseCounter(nil)
+}
+
+func TestPreciseCounterCompensated(t *testing.T) {
+	t.Parallel()
+
+	c := AcquirePreciseCounter()
+	defer ReleasePreciseCounter(c)
+
+	naive := AcquireCounter()
+	defer ReleaseCounter(naive)
+
+	for i := 0; i < 1000000; i++ {
+		c.Add(0.1)
+		naive.Add(0.1)
+	}
+
+	if v := c.Get(); v != 100000 {
+		t.Fatalf("should be %d, but %.10f", 100000, v)
+	}
+
+	// make sure the test means something
+	if v := naive.Get(); v == 100000 {
+		t.Fatal("naive sum should drift, but not")
+	}
+
+	// large and small
+	c.Set(1e16)
+	c.Add(1)
+	c.Add(-1e16)
+	if v := c.Get(); v != 1 {
+		t.Fatalf("should be %d, but %f", 1, v)
+	}
+}
+
+func TestPreciseCounterChange(t *testing.T) {
+	t.Parallel()
+
+	c := AcquirePreciseCounter()
+	defer ReleasePreciseCounter(c)
+
+	wg := sync.WaitGroup{}
+	wg.Add(200)
+	for i := 0; i < 100; i++ {
+		go func() {
+			c.Inc()
+			wg.Done()
+		}()
+		go func() {
+			c.Sub(0.5)
+			wg.Done()
+		}()
+	}
+
+	wg.Wait()
+
+	if v := c.Get(); v != 50 {
+		t.Fatalf("should be %d, but %f", 50, v)
+	}
+
+	c.Sub(100)
+	if v := c.Get(); v != 0 {
+		t.Fatalf("should be %d, but %f", 0, v)
+	}
+	if v := c.Real(); v != -50 {
+		t.Fatalf("should be %d, but %f", -50, v)
+	}
+
+	c.Reset()
+	if v := c.Real(); v != 0 {
+		t.Fatalf("should be %d, but %f", 0, v)
+	}
+}
+
+func TestPreciseCounterCopyTo(t *testing.T) {
+	t.Parallel()
+
+	c1 := AcquirePreciseCounter()
+	c2 := AcquirePreciseCounter()
+	defer ReleasePreciseCounter(c1)
+	defer ReleasePreciseCounter(c2)
+
+	c1.Add(2.5)
+
+	ok, err := c1.CopyTo(c1)
+	if ok || err != ErrSameCounterPointer {
+		t.Fatalf("same counter should err, but %v", err)
+	}
+
+	ok, err = c1.CopyTo(1)
+	if ok || err != ErrDifferentCounterType {
+		t.Fatalf("different counter should err, but %v", err)
+	}
+
+	ok, err = c1.CopyTo(c2)
+	if !ok || err != nil {
+		t.Fatalf("counter should be copied, but err: %v", err)
+	}
+	if v := c2.Get(); v != 2.5 {
+		t.Fatalf("should be %f, but %f", 2.5, v)
+	}
+}
@@ -0,0 +1,166 @@
+package gounter
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PrometheusContentType is the content type of the Prometheus text exposition format.
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// sample is a value of a metric, with the label if it is from a LabelCounter.
+type sample struct {
+	label    string
+	labelled bool
+	value    float64
+	counter  Gounter
+}
+
+// max returns the max of the sample if its counter is a MaxCounter.
+func (s sample) max() (float64, bool) {
+	if c, ok := s.counter.(*MaxCounter); ok {
+		return c.GetMax(), true
+	}
+
+	return 0, false
+}
+
+// samples returns the values of the metric, sorted by label.
+// Gauges have the real values, Get clamps negative values to 0.
+func (m *metric) samples() []sample {
+	value := func(c Gounter, v float64) float64 {
+		if m.desc.Type == MetricGauge {
+			return realOf(c)
+		}
+
+		return v
+	}
+
+	switch c := m.value.(type) {
+	case labelRanger:
+		samples := make([]sample, 0)
+		c.rangeGounter(func(label string, v float64, g Gounter) bool {
+			samples = append(samples, sample{label: label, labelled: true, value: value(g, v), counter: g})
+			return true
+		})
+
+		sort.Slice(samples, func(i, j int) bool {
+			return samples[i].label < samples[j].label
+		})
+
+		return samples
+	case Gounter:
+		return []sample{{value: value(c, c.Get()), counter: c}}
+	}
+
+	return nil
+}
+
+// formatFloat formats the value for the text formats.
+func formatFloat(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// helpEscaper escapes the help text.
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+// labelEscaper escapes the label value.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+// writeSeries writes a line of `name{label="value"} v`.
+func writeSeries(w *bufio.Writer, name string, desc MetricDesc, s sample, v float64) {
+	w.WriteString(name)
+	if s.labelled {
+		w.WriteString("{")
+		w.WriteString(desc.LabelName)
+		w.WriteString(`="`)
+		labelEscaper.WriteString(w, s.label)
+		w.WriteString(`"}`)
+	}
+	w.WriteString(" ")
+	w.WriteString(formatFloat(v))
+	w.WriteString("\n")
+}
+
+// writeHeader writes the HELP and TYPE lines.
+func writeHeader(w *bufio.Writer, name, help string, typ MetricType) {
+	if help != "" {
+		w.WriteString("# HELP ")
+		w.WriteString(name)
+		w.WriteString(" ")
+		helpEscaper.WriteString(w, help)
+		w.WriteString("\n")
+	}
+
+	w.WriteString("# TYPE ")
+	w.WriteString(name)
+	w.WriteString(" ")
+	w.WriteString(string(typ))
+	w.WriteString("\n")
+}
+
+// WritePrometheus writes all registered metrics in the Prometheus text exposition format,
+// sorted by name. The max of MaxCounter is written as a companion gauge `<name>_max`.
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	for _, m := range r.sortedMetrics() {
+		samples := m.samples()
+
+		writeHeader(bw, m.desc.Name, m.desc.Help, m.desc.Type)
+		for _, s := range samples {
+			writeSeries(bw, m.desc.Name, m.desc, s, s.value)
+		}
+
+		// companion gauge of MaxCounter
+		maxName := m.desc.Name + "_max"
+		header := false
+		for _, s := range samples {
+			max, ok := s.max()
+			if !ok {
+				continue
+			}
+
+			if !header {
+				writeHeader(bw, maxName, "Max of "+m.desc.Name+".", MetricGauge)
+				header = true
+			}
+			writeSeries(bw, maxName, m.desc, s, max)
+		}
+	}
+
+	return bw.Flush()
+}
+
+// Handler returns a http.Handler serving the registered metrics
+// in the Prometheus text exposition format,
+// or in the OpenMetrics text format if the request accepts it.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		write := r.WritePrometheus
+		contentType := PrometheusContentType
+		if strings.Contains(req.Header.Get("Accept"), "application/openmetrics-text") {
+			write = r.WriteOpenMetrics
+			contentType = OpenMetricsContentType
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		if err := write(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
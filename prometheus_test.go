@@ -0,0 +1,108 @@
+package gounter
+
+import (
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWritePrometheus(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+
+	requests := AcquireCounter()
+	defer ReleaseCounter(requests)
+	requests.Add(1027)
+
+	conns := AcquireMaxCounter(100)
+	defer ReleaseMaxCounter(conns)
+	conns.Add(3)
+
+	codes := NewLabelCounterNormal()
+	codes.Add("200", 10)
+	codes.Add("500", 1)
+	codes.Set(`a"b\c`+"\n", math.Inf(1))
+
+	quotas := NewLabelCounterWithMax(5)
+	quotas.Inc("x")
+
+	// negative gauges
+	temp := AcquireCounter()
+	defer ReleaseCounter(temp)
+	temp.Set(-3)
+
+	offsets := NewLabelCounterNormal()
+	offsets.Add("a", -2)
+
+	for _, reg := range []struct {
+		desc MetricDesc
+		c    any
+	}{
+		{MetricDesc{Name: "http_requests_total", Help: "The total number of HTTP requests."}, requests},
+		{MetricDesc{Name: "connections", Help: "Open connections.\nWith \\ escape.", Type: MetricGauge}, conns},
+		{MetricDesc{Name: "http_codes_total", LabelName: "code"}, codes},
+		{MetricDesc{Name: "quota", Type: MetricGauge}, quotas},
+		{MetricDesc{Name: "temp", Type: MetricGauge}, temp},
+		{MetricDesc{Name: "offsets", Type: MetricGauge}, offsets},
+	} {
+		if err := r.Register(reg.desc, reg.c); err != nil {
+			t.Fatalf("should be nil, but %v", err)
+		}
+	}
+
+	out := &strings.Builder{}
+	if err := r.WritePrometheus(out); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+
+	want := `# HELP connections Open connections.\nWith \\ escape.
+# TYPE connections gauge
+connections 3
+# HELP connections_max Max of connections.
+# TYPE connections_max gauge
+connections_max 100
+# TYPE http_codes_total counter
+http_codes_total{code="200"} 10
+http_codes_total{code="500"} 1
+http_codes_total{code="a\"b\\c\n"} +Inf
+# HELP http_requests_total The total number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total 1027
+# TYPE offsets gauge
+offsets{label="a"} -2
+# TYPE quota gauge
+quota{label="x"} 1
+# HELP quota_max Max of quota.
+# TYPE quota_max gauge
+quota_max{label="x"} 5
+# TYPE temp gauge
+temp -3
+`
+	if got := out.String(); got != want {
+		t.Fatalf("wrong result, expect\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestRegistryHandler(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+	c := NewShardedLabelCounterNormal(2)
+	c.Inc("a")
+	if err := r.Register(MetricDesc{Name: "sharded_total"}, c); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != PrometheusContentType {
+		t.Errorf("should be %s, but %s", PrometheusContentType, ct)
+	}
+	want := "# TYPE sharded_total counter\nsharded_total{label=\"a\"} 1\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("wrong result, expect\n%s\ngot\n%s", want, got)
+	}
+}
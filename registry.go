@@ -0,0 +1,299 @@
+package gounter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetricType is the type of a registered metric.
+type MetricType string
+
+const (
+	// MetricCounter is a value only going up.
+	MetricCounter MetricType = "counter"
+	// MetricGauge is a value going up and down.
+	MetricGauge MetricType = "gauge"
+)
+
+// defaultLabelName is the label name of LabelCounter when it is not given.
+const defaultLabelName = "label"
+
+// MetricDesc describes a registered metric.
+type MetricDesc struct {
+	// Name is the metric name, [a-zA-Z_:][a-zA-Z0-9_:]*.
+	Name string
+	// Help is the help text.
+	Help string
+	// Type is MetricCounter by default.
+	Type MetricType
+	// LabelName is the label name of a LabelCounter, "label" by default.
+	LabelName string
+	// Unit is the unit, like "seconds", only written by OpenMetrics.
+	// The name must have the unit as suffix, before `_total` for counters.
+	Unit string
+}
+
+// family returns the OpenMetrics metric family name,
+// which is the name without `_total` for counters.
+func (desc MetricDesc) family() string {
+	if desc.Type == MetricCounter {
+		return strings.TrimSuffix(desc.Name, "_total")
+	}
+
+	return desc.Name
+}
+
+// labelRanger is implemented by LabelCounter and ShardedLabelCounter.
+type labelRanger interface {
+	rangeGounter(f func(label string, v float64, c Gounter) bool)
+}
+
+// metric is a registered metric.
+type metric struct {
+	desc    MetricDesc
+	value   any
+	created time.Time
+}
+
+// Registry holds counters registered with a metric name,
+// so they can be exported.
+// Gounter, LabelCounter and ShardedLabelCounter can be registered.
+type Registry struct {
+	noCopy noCopy
+
+	metrics map[string]*metric
+	mux     sync.RWMutex
+}
+
+// DefaultRegistry is the process-wide Registry.
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry creates and returns a new Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics: make(map[string]*metric),
+	}
+}
+
+// validMetricName checks the name is [a-zA-Z_:][a-zA-Z0-9_:]*.
+func validMetricName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// validLabelName checks the name is [a-zA-Z_][a-zA-Z0-9_]*.
+func validLabelName(name string) bool {
+	for i, r := range name {
+		if r == ':' {
+			return false
+		}
+		if r >= '0' && r <= '9' && i == 0 {
+			return false
+		}
+	}
+
+	return validMetricName(name)
+}
+
+// validDesc checks the desc and the counter, and fills the defaults of the desc.
+func validDesc(desc MetricDesc, c any) (MetricDesc, error) {
+	if !validMetricName(desc.Name) {
+		return desc, ErrInvalidMetricName
+	}
+
+	if desc.Type == "" {
+		desc.Type = MetricCounter
+	}
+
+	if desc.LabelName == "" {
+		desc.LabelName = defaultLabelName
+	}
+
+	if !validLabelName(desc.LabelName) {
+		return desc, ErrInvalidLabelName
+	}
+
+	if desc.Unit != "" && (!validLabelName(desc.Unit) || !strings.HasSuffix(desc.family(), "_"+desc.Unit)) {
+		return desc, ErrInvalidMetricUnit
+	}
+
+	switch c.(type) {
+	case Gounter, labelRanger:
+	default:
+		return desc, ErrUnsupportedCounter
+	}
+
+	return desc, nil
+}
+
+// Register registers the counter with the desc.
+// c must be a Gounter, a LabelCounter or a ShardedLabelCounter.
+func (r *Registry) Register(desc MetricDesc, c any) error {
+	desc, err := validDesc(desc, c)
+	if err != nil {
+		return err
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if _, ok := r.metrics[desc.Name]; ok {
+		return ErrMetricAlreadyRegistered
+	}
+
+	r.metrics[desc.Name] = &metric{desc: desc, value: c, created: time.Now()}
+	return nil
+}
+
+// Unregister removes the metric of the name,
+// returns false if it is not registered.
+func (r *Registry) Unregister(name string) bool {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if _, ok := r.metrics[name]; !ok {
+		return false
+	}
+
+	delete(r.metrics, name)
+	return true
+}
+
+// Get returns the counter registered with the name.
+func (r *Registry) Get(name string) (c any, ok bool) {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	m, ok := r.metrics[name]
+	if !ok {
+		return nil, false
+	}
+
+	return m.value, true
+}
+
+// Names returns the registered metric names, sorted.
+func (r *Registry) Names() []string {
+	metrics := r.sortedMetrics()
+
+	names := make([]string, len(metrics))
+	for i, m := range metrics {
+		names[i] = m.desc.Name
+	}
+
+	return names
+}
+
+// Range calls f for each registered metric sorted by name,
+// stops if f returns false.
+func (r *Registry) Range(f func(desc MetricDesc, c any) bool) {
+	for _, m := range r.sortedMetrics() {
+		if !f(m.desc, m.value) {
+			return
+		}
+	}
+}
+
+// CounterTypeError is returned when a name is already registered with a different type.
+// It unwraps to ErrDifferentCounterType.
+type CounterTypeError struct {
+	Name string
+	// Want is the type asked for, Got is the type registered.
+	Want, Got string
+}
+
+// Error implements error.
+func (e *CounterTypeError) Error() string {
+	return "metric " + e.Name + " is registered as " + e.Got + ", not " + e.Want
+}
+
+// Unwrap returns ErrDifferentCounterType.
+func (e *CounterTypeError) Unwrap() error {
+	return ErrDifferentCounterType
+}
+
+// GetOrCreate returns the counter registered with the desc name,
+// or registers the one returned by create.
+// A *CounterTypeError is returned if the name is registered
+// with a different counter type or metric type.
+func GetOrCreate[T any](r *Registry, desc MetricDesc, create func() T) (T, error) {
+	var zero T
+
+	desc, err := validDesc(desc, zero)
+	if err != nil && err != ErrUnsupportedCounter {
+		return zero, err
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if m, ok := r.metrics[desc.Name]; ok {
+		c, ok := m.value.(T)
+		if !ok || m.desc.Type != desc.Type {
+			return zero, &CounterTypeError{
+				Name: desc.Name,
+				Want: fmt.Sprintf("%s %T", desc.Type, zero),
+				Got:  fmt.Sprintf("%s %T", m.desc.Type, m.value),
+			}
+		}
+
+		return c, nil
+	}
+
+	c := create()
+	if _, err := validDesc(desc, c); err != nil {
+		return zero, err
+	}
+
+	r.metrics[desc.Name] = &metric{desc: desc, value: c, created: time.Now()}
+	return c, nil
+}
+
+// Counter returns the Counter of the name, created if it is not registered.
+func (r *Registry) Counter(desc MetricDesc) (*Counter, error) {
+	return GetOrCreate(r, desc, AcquireCounter)
+}
+
+// MaxCounter returns the MaxCounter of the name, created with max if it is not registered.
+func (r *Registry) MaxCounter(desc MetricDesc, max float64) (*MaxCounter, error) {
+	return GetOrCreate(r, desc, func() *MaxCounter {
+		return AcquireMaxCounter(max)
+	})
+}
+
+// LabelCounter returns the LabelCounter of the name, created if it is not registered.
+func (r *Registry) LabelCounter(desc MetricDesc) (*LabelCounter[*Counter], error) {
+	return GetOrCreate(r, desc, NewLabelCounterNormal)
+}
+
+// sortedMetrics returns the registered metrics sorted by name.
+func (r *Registry) sortedMetrics() []*metric {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	metrics := make([]*metric, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		metrics = append(metrics, m)
+	}
+
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].desc.Name < metrics[j].desc.Name
+	})
+
+	return metrics
+}
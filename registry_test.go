@@ -0,0 +1,146 @@
+package gounter
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestRegistryRegisterErrors(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+	c := AcquireCounter()
+	defer ReleaseCounter(c)
+
+	tests := []struct {
+		desc MetricDesc
+		c    any
+		err  error
+	}{
+		{MetricDesc{Name: ""}, c, ErrInvalidMetricName},
+		{MetricDesc{Name: "0abc"}, c, ErrInvalidMetricName},
+		{MetricDesc{Name: "a-b"}, c, ErrInvalidMetricName},
+		{MetricDesc{Name: "ok", LabelName: "a:b"}, c, ErrInvalidLabelName},
+		{MetricDesc{Name: "ok", LabelName: "1a"}, c, ErrInvalidLabelName},
+		{MetricDesc{Name: "ok"}, 1, ErrUnsupportedCounter},
+		{MetricDesc{Name: "ns:ok_1"}, c, nil},
+		{MetricDesc{Name: "ns:ok_1"}, c, ErrMetricAlreadyRegistered},
+	}
+	for _, tt := range tests {
+		if err := r.Register(tt.desc, tt.c); err != tt.err {
+			t.Errorf("%s, should be %v, but %v", tt.desc.Name, tt.err, err)
+		}
+	}
+}
+
+func TestRegistryGetOrCreate(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+
+	c1, err := r.Counter(MetricDesc{Name: "requests_total"})
+	if err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+	c2, err := r.Counter(MetricDesc{Name: "requests_total"})
+	if err != nil || c1 != c2 {
+		t.Fatalf("should be same counter, but %p %p %v", c1, c2, err)
+	}
+
+	l1, _ := r.LabelCounter(MetricDesc{Name: "codes_total", LabelName: "code"})
+	l2, _ := r.LabelCounter(MetricDesc{Name: "codes_total", LabelName: "code"})
+	if l1 != l2 {
+		t.Fatal("should be same label counter")
+	}
+
+	// different counter type
+	_, err = r.MaxCounter(MetricDesc{Name: "requests_total"}, 10)
+	var typeErr *CounterTypeError
+	if !errors.As(err, &typeErr) || !errors.Is(err, ErrDifferentCounterType) {
+		t.Fatalf("should be CounterTypeError, but %v", err)
+	}
+	if typeErr.Name != "requests_total" {
+		t.Errorf("should be requests_total, but %s", typeErr.Name)
+	}
+
+	// different metric type
+	if _, err := r.Counter(MetricDesc{Name: "requests_total", Type: MetricGauge}); !errors.Is(err, ErrDifferentCounterType) {
+		t.Errorf("should be %v, but %v", ErrDifferentCounterType, err)
+	}
+
+	if _, err := r.Counter(MetricDesc{Name: "0bad"}); err != ErrInvalidMetricName {
+		t.Errorf("should be %v, but %v", ErrInvalidMetricName, err)
+	}
+	if _, err := GetOrCreate(r, MetricDesc{Name: "bad"}, func() int { return 1 }); err != ErrUnsupportedCounter {
+		t.Errorf("should be %v, but %v", ErrUnsupportedCounter, err)
+	}
+}
+
+func TestRegistryUnregister(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+	c, _ := r.Counter(MetricDesc{Name: "b_total"})
+	r.Counter(MetricDesc{Name: "a_total"})
+
+	if got, ok := r.Get("b_total"); !ok || got != c {
+		t.Fatalf("should be %p, but %v", c, got)
+	}
+
+	names := r.Names()
+	if len(names) != 2 || names[0] != "a_total" || names[1] != "b_total" {
+		t.Fatalf("wrong names, got %v", names)
+	}
+
+	count := 0
+	r.Range(func(desc MetricDesc, c any) bool {
+		count++
+		return false
+	})
+	if count != 1 {
+		t.Errorf("should be 1, but %d", count)
+	}
+
+	if !r.Unregister("b_total") {
+		t.Fatal("should be true")
+	}
+	if r.Unregister("b_total") {
+		t.Fatal("should be false")
+	}
+	if _, ok := r.Get("b_total"); ok {
+		t.Fatal("should be unregistered")
+	}
+
+	// a new one after unregister
+	if c2, _ := r.Counter(MetricDesc{Name: "b_total"}); c2 == c {
+		t.Fatal("should be a new counter")
+	}
+}
+
+func TestRegistryGetOrCreateConcurrent(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+
+	var wg sync.WaitGroup
+	counters := make([]*Counter, 8)
+	for i := range counters {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			counters[i], _ = r.Counter(MetricDesc{Name: "shared_total"})
+			counters[i].Inc()
+		}(i)
+	}
+	wg.Wait()
+
+	for _, c := range counters {
+		if c != counters[0] {
+			t.Fatal("should be same counter")
+		}
+	}
+	if v := counters[0].Get(); v != 8 {
+		t.Errorf("should be 8, but %f", v)
+	}
+}
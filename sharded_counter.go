@@ -0,0 +1,189 @@
+package gounter
+
+import (
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// maxShards limits the number of cells of a ShardedCounter.
+const maxShards = 256
+
+// shardCell is one padded cell of ShardedCounter.
+// The padding keeps every cell on its own cache line.
+type shardCell struct {
+	bits uint64
+	_    [56]byte
+}
+
+// shardToken holds the cell index used by a goroutine.
+// Tokens live in a sync.Pool, which is per-P,
+// so goroutines on the same P tend to reuse the same cell.
+type shardToken struct {
+	idx uint32
+}
+
+// shardTokenPool is a pool for shardToken.
+var shardTokenPool = &sync.Pool{
+	New: func() any {
+		return &shardToken{idx: rand.Uint32()}
+	},
+}
+
+// ShardedCounter is a Counter for write-heavy hot paths.
+// Writes are spread across padded cells,
+// and the cells are folded on `Get()` and `Real()`.
+//
+// Reading is slower than Counter, because all cells are summed.
+// Like Counter, `Get()` returns 0 when the value is negative.
+//
+// Copying is prohibited. Please acquire new object.
+type ShardedCounter struct {
+	noCopy noCopy
+
+	cells []shardCell
+	mask  uint32
+}
+
+// shardedCounterPool is a pool for ShardedCounter.
+var shardedCounterPool = &sync.Pool{
+	New: func() any {
+		return newShardedCounter()
+	},
+}
+
+// newShardedCounter creates a ShardedCounter,
+// the number of cells is the power of two not less than GOMAXPROCS.
+func newShardedCounter() *ShardedCounter {
+	n := 1
+	procs := runtime.GOMAXPROCS(0)
+	for n < procs && n < maxShards {
+		n <<= 1
+	}
+
+	return &ShardedCounter{
+		cells: make([]shardCell, n),
+		mask:  uint32(n - 1),
+	}
+}
+
+// AcquireShardedCounter return a ShardedCounter Pointer.
+func AcquireShardedCounter() *ShardedCounter {
+	return shardedCounterPool.Get().(*ShardedCounter)
+}
+
+// ReleaseShardedCounter releases a ShardedCounter Pointer.
+func ReleaseShardedCounter(c *ShardedCounter) {
+	if c == nil {
+		return
+	}
+	c.reset()
+	shardedCounterPool.Put(c)
+}
+
+// reset ShardedCounter to release.
+func (c *ShardedCounter) reset() {
+	for i := range c.cells {
+		atomic.StoreUint64(&c.cells[i].bits, 0)
+	}
+}
+
+// Get returns a number.
+// When the counter value is negative, it returns 0.
+func (c *ShardedCounter) Get() float64 {
+	val := c.Real()
+	if val < 0 {
+		return 0
+	}
+
+	return val
+}
+
+// Real returns the sum of all cells.
+func (c *ShardedCounter) Real() float64 {
+	var val float64
+	for i := range c.cells {
+		bits := atomic.LoadUint64(&c.cells[i].bits)
+		val += math.Float64frombits(bits)
+	}
+
+	return val
+}
+
+// Inc increases the counter by 1.
+// ShardedCounter always returns true.
+func (c *ShardedCounter) Inc() bool {
+	return c.Add(1)
+}
+
+// Dec decreases the counter by 1.
+// ShardedCounter always returns true.
+func (c *ShardedCounter) Dec() bool {
+	return c.Add(-1)
+}
+
+// Set sets the value of the counter.
+// The first cell gets the value and the others are cleared,
+// concurrent Add calls during Set may be lost.
+func (c *ShardedCounter) Set(value float64) bool {
+	for i := 1; i < len(c.cells); i++ {
+		atomic.StoreUint64(&c.cells[i].bits, 0)
+	}
+	atomic.StoreUint64(&c.cells[0].bits, math.Float64bits(value))
+
+	return true
+}
+
+// Add increases the counter number.
+// Decreasing use negative number.
+// When the cell is contended, the goroutine moves to another cell.
+// ShardedCounter always returns true.
+func (c *ShardedCounter) Add(delta float64) bool {
+	token := shardTokenPool.Get().(*shardToken)
+	defer shardTokenPool.Put(token)
+
+	for {
+		cell := &c.cells[token.idx&c.mask]
+
+		oldBits := atomic.LoadUint64(&cell.bits)
+		newVal := math.Float64frombits(oldBits) + delta
+		newBits := math.Float64bits(newVal)
+		if atomic.CompareAndSwapUint64(&cell.bits, oldBits, newBits) {
+			return true
+		}
+
+		// contended, rehash
+		token.idx = token.idx*1664525 + 1013904223
+	}
+}
+
+// Sub decreases the counter number.
+// ShardedCounter always returns true.
+func (c *ShardedCounter) Sub(delta float64) bool {
+	return c.Add(delta * -1)
+}
+
+// Reset resets this ShardedCounter.
+func (c *ShardedCounter) Reset() {
+	c.reset()
+}
+
+// CopyTo copies a ShardedCounter to other ShardedCounter.
+// The folded value is copied, cells of dst are not kept.
+func (c *ShardedCounter) CopyTo(d interface{}) (ok bool, err error) {
+	dst, can := d.(*ShardedCounter)
+	if !can {
+		err = ErrDifferentCounterType
+		return
+	}
+
+	if c == dst {
+		err = ErrSameCounterPointer
+		return
+	}
+
+	ok = dst.Set(c.Real())
+	return
+}
@@ -0,0 +1,130 @@
+package gounter
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestShardedCounterReleaseNil(t *testing.T) {
+	ReleaseShardedCounter(nil)
+}
+
+func TestShardedCounterChange(t *testing.T) {
+	t.Parallel()
+
+	testShardedCounterIncAndDec(t)
+
+	testGo(t, testShardedCounterIncAndDec, 10)
+	testGo(t, testShardedCounterIncAndDec, 100)
+}
+
+func TestShardedCounterSetAndReset(t *testing.T) {
+	t.Parallel()
+
+	c := AcquireShardedCounter()
+	defer ReleaseShardedCounter(c)
+
+	c.Add(10)
+	c.Set(3)
+	if v := c.Get(); v != 3 {
+		t.Fatalf("should be %d, but %f", 3, v)
+	}
+
+	c.Sub(5)
+	if v := c.Get(); v != 0 {
+		t.Fatalf("should be %d, but %f", 0, v)
+	}
+	if v := c.Real(); v != -2 {
+		t.Fatalf("should be %d, but %f", -2, v)
+	}
+
+	c.Reset()
+	if v := c.Real(); v != 0 {
+		t.Fatalf("should be %d, but %f", 0, v)
+	}
+}
+
+func TestShardedCounterCopyTo(t *testing.T) {
+	t.Parallel()
+
+	c1 := AcquireShardedCounter()
+	c2 := AcquireShardedCounter()
+	defer ReleaseShardedCounter(c1)
+	defer ReleaseShardedCounter(c2)
+
+	c1.Add(42)
+
+	ok, err := c1.CopyTo(c1)
+	if ok || err != ErrSameCounterPointer {
+		t.Fatalf("same counter should err, but %v", err)
+	}
+
+	ok, err = c1.CopyTo(AcquireCounter())
+	if ok || err != ErrDifferentCounterType {
+		t.Fatalf("different counter should err, but %v", err)
+	}
+
+	ok, err = c1.CopyTo(c2)
+	if !ok || err != nil {
+		t.Fatalf("counter should be copied, but err: %v", err)
+	}
+	if v := c2.Get(); v != 42 {
+		t.Fatalf("should be %d, but %f", 42, v)
+	}
+}
+
+func TestLabelCounterWithSharded(t *testing.T) {
+	t.Parallel()
+
+	labels := testGenerateLabels()
+
+	c := NewLabelCounterSharded()
+	testLabelCounterIncDec(labels, 1000, c, t)
+}
+
+func testShardedCounterIncAndDec(t *testing.T) {
+	c := AcquireShardedCounter()
+	defer ReleaseShardedCounter(c)
+
+	wg := sync.WaitGroup{}
+	wg.Add(200)
+	for i := 0; i < 100; i++ {
+		go func() {
+			c.Inc()
+			c.Inc()
+			wg.Done()
+		}()
+		go func() {
+			c.Dec()
+			wg.Done()
+		}()
+	}
+
+	wg.Wait()
+
+	if v := c.Get(); v != 100 {
+		t.Fatalf("should be %d, but %f", 100, v)
+	}
+}
+
+func BenchmarkCounterInc(b *testing.B) {
+	c := AcquireCounter()
+	defer ReleaseCounter(c)
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Inc()
+		}
+	})
+}
+
+func BenchmarkShardedCounterInc(b *testing.B) {
+	c := AcquireShardedCounter()
+	defer ReleaseShardedCounter(c)
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Inc()
+		}
+	})
+}
@@ -0,0 +1,184 @@
+package gounter
+
+import "time"
+
+// defaultLabelShards is the number of shards when it is not given.
+const defaultLabelShards = 16
+
+// ShardedLabelCounter partitions labels across independent LabelCounter shards
+// by the hash of the label, so operations on different shards do not contend.
+// It implements LabelGounter.
+//
+// Cross-shard reads (Len, Labels, Range, Snapshot) lock one shard at a time,
+// they are consistent per shard, not across shards.
+type ShardedLabelCounter[T Gounter] struct {
+	noCopy noCopy
+
+	shards []*LabelCounter[T]
+}
+
+// NewShardedLabelCounter creates and returns a new ShardedLabelCounter with n shards.
+// If n is not positive, 16 shards are used.
+func NewShardedLabelCounter[T Gounter](n int, acq func() T, rel func(T)) *ShardedLabelCounter[T] {
+	if n <= 0 {
+		n = defaultLabelShards
+	}
+
+	shards := make([]*LabelCounter[T], n)
+	for i := range shards {
+		shards[i] = NewLabelCounter[T](acq, rel)
+	}
+
+	return &ShardedLabelCounter[T]{
+		shards: shards,
+	}
+}
+
+// NewShardedLabelCounterNormal returns a new ShardedLabelCounter with Counter as the underlying type.
+func NewShardedLabelCounterNormal(n int) *ShardedLabelCounter[*Counter] {
+	return NewShardedLabelCounter[*Counter](n, AcquireCounter, ReleaseCounter)
+}
+
+// shard returns the shard of the label.
+func (counter *ShardedLabelCounter[T]) shard(label string) *LabelCounter[T] {
+	return counter.shards[counter.shardOf(label)]
+}
+
+// shardOf returns the index of the shard of the label, by FNV-1a hash.
+func (counter *ShardedLabelCounter[T]) shardOf(label string) int {
+	var h uint32 = 2166136261
+	for i := 0; i < len(label); i++ {
+		h ^= uint32(label[i])
+		h *= 16777619
+	}
+
+	return int(h % uint32(len(counter.shards)))
+}
+
+// Observe adds observers to every shard.
+func (counter *ShardedLabelCounter[T]) Observe(observers ...Observer) {
+	for _, shard := range counter.shards {
+		shard.Observe(observers...)
+	}
+}
+
+// Get returns the value and the Gounter associated with the given label.
+func (counter *ShardedLabelCounter[T]) Get(label string) (float64, T) {
+	return counter.shard(label).Get(label)
+}
+
+// Reset resets every shard to an empty state.
+func (counter *ShardedLabelCounter[T]) Reset() {
+	for _, shard := range counter.shards {
+		shard.Reset()
+	}
+}
+
+// Close closes every shard, see LabelCounter.Close.
+func (counter *ShardedLabelCounter[T]) Close() (err error) {
+	for _, shard := range counter.shards {
+		if e := shard.Close(); e != nil {
+			err = e
+		}
+	}
+
+	return
+}
+
+// ResetLabel resets the counter for the given label to zero.
+func (counter *ShardedLabelCounter[T]) ResetLabel(label string) {
+	counter.shard(label).ResetLabel(label)
+}
+
+// RemoveLabel removes the label and releases its counter.
+func (counter *ShardedLabelCounter[T]) RemoveLabel(label string) {
+	counter.shard(label).RemoveLabel(label)
+}
+
+// Set sets the value of the Gounter associated with the given label.
+func (counter *ShardedLabelCounter[T]) Set(label string, v float64) (bool, T) {
+	return counter.shard(label).Set(label, v)
+}
+
+// Add adds the given delta to the counter for the given label.
+func (counter *ShardedLabelCounter[T]) Add(label string, delta float64) (bool, T) {
+	return counter.shard(label).Add(label, delta)
+}
+
+// Sub subtracts the given delta from the counter for the given label.
+func (counter *ShardedLabelCounter[T]) Sub(label string, delta float64) (bool, T) {
+	return counter.shard(label).Sub(label, delta)
+}
+
+// Inc increments the counter for the given label by one.
+func (counter *ShardedLabelCounter[T]) Inc(label string) (bool, T) {
+	return counter.shard(label).Inc(label)
+}
+
+// Dec decrements the counter for the given label by one.
+func (counter *ShardedLabelCounter[T]) Dec(label string) (bool, T) {
+	return counter.shard(label).Dec(label)
+}
+
+// labelCreated returns the time the label is created,
+// false if the label is not found.
+func (counter *ShardedLabelCounter[T]) labelCreated(label string) (time.Time, bool) {
+	return counter.shard(label).labelCreated(label)
+}
+
+// Len returns the number of labels.
+func (counter *ShardedLabelCounter[T]) Len() int {
+	n := 0
+	for _, shard := range counter.shards {
+		n += shard.Len()
+	}
+
+	return n
+}
+
+// Labels returns all labels.
+func (counter *ShardedLabelCounter[T]) Labels() []string {
+	labels := make([]string, 0)
+	for _, shard := range counter.shards {
+		labels = append(labels, shard.Labels()...)
+	}
+
+	return labels
+}
+
+// Range calls f for each label of every shard with its value and Gounter,
+// until f returns false.
+// It holds the read lock of a shard, f must not change labels of the counter.
+func (counter *ShardedLabelCounter[T]) Range(f func(label string, v float64, c T) bool) {
+	next := true
+	for _, shard := range counter.shards {
+		shard.Range(func(label string, v float64, c T) bool {
+			next = f(label, v, c)
+			return next
+		})
+
+		if !next {
+			return
+		}
+	}
+}
+
+// rangeGounter is Range with Gounter, for callers not knowing T.
+func (counter *ShardedLabelCounter[T]) rangeGounter(f func(label string, v float64, c Gounter) bool) {
+	counter.Range(func(label string, v float64, c T) bool {
+		return f(label, v, c)
+	})
+}
+
+// Snapshot returns the values of all labels.
+func (counter *ShardedLabelCounter[T]) Snapshot() map[string]float64 {
+	snapshot := make(map[string]float64)
+	for _, shard := range counter.shards {
+		shard.Range(func(label string, v float64, c T) bool {
+			snapshot[label] = v
+			return true
+		})
+	}
+
+	return snapshot
+}
@@ -0,0 +1,109 @@
+package gounter
+
+import (
+	"sort"
+	"strconv"
+	"testing"
+)
+
+var _ LabelGounter[*Counter] = (*ShardedLabelCounter[*Counter])(nil)
+
+func TestShardedLabelCounterIncDec(t *testing.T) {
+	t.Parallel()
+
+	labels := testGenerateLabels()
+
+	c := NewShardedLabelCounterNormal(4)
+	for _, label := range labels {
+		c.Add(label, 0)
+	}
+	wg := make(chan struct{}, len(labels))
+	for _, label := range labels {
+		go func(ll string) {
+			for i := 0; i < 100; i++ {
+				c.Inc(ll)
+			}
+			for i := 0; i < 50; i++ {
+				c.Dec(ll)
+			}
+			wg <- struct{}{}
+		}(label)
+	}
+	for range labels {
+		<-wg
+	}
+
+	for _, label := range labels {
+		if v, _ := c.Get(label); v != 50 {
+			t.Errorf("label %s, wrong result, expect %d, got %f", label, 50, v)
+		}
+	}
+}
+
+func TestShardedLabelCounterIterate(t *testing.T) {
+	t.Parallel()
+
+	c := NewShardedLabelCounter[*Counter](0, AcquireCounter, ReleaseCounter)
+	if n := len(c.shards); n != defaultLabelShards {
+		t.Fatalf("wrong result, expect %d, got %d", defaultLabelShards, n)
+	}
+
+	for i := 0; i < 100; i++ {
+		c.Set(strconv.Itoa(i), float64(i))
+	}
+	c.RemoveLabel("0")
+	c.ResetLabel("1")
+
+	if n := c.Len(); n != 99 {
+		t.Errorf("wrong result, expect %d, got %d", 99, n)
+	}
+
+	labels := c.Labels()
+	sort.Strings(labels)
+	if len(labels) != 99 || labels[0] != "1" {
+		t.Errorf("wrong result, expect %d labels from %s, got %v", 99, "1", labels)
+	}
+
+	snapshot := c.Snapshot()
+	if len(snapshot) != 99 || snapshot["1"] != 0 || snapshot["99"] != 99 {
+		t.Errorf("wrong snapshot, got %v", snapshot)
+	}
+
+	n := 0
+	c.Range(func(label string, v float64, cc *Counter) bool {
+		n++
+		return n < 10
+	})
+	if n != 10 {
+		t.Errorf("wrong result, expect %d, got %d", 10, n)
+	}
+
+	c.Reset()
+	if n := c.Len(); n != 0 {
+		t.Errorf("wrong result, expect %d, got %d", 0, n)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Errorf("wrong result, expect %v, got %v", nil, err)
+	}
+	if err := c.Close(); err != ErrLabelCounterClosed {
+		t.Errorf("wrong result, expect %v, got %v", ErrLabelCounterClosed, err)
+	}
+}
+
+func BenchmarkShardedLabelCounterInc(b *testing.B) {
+	labels := testGenerateLabels()
+
+	c := NewShardedLabelCounterNormal(0)
+	for _, label := range labels {
+		c.Inc(label)
+	}
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.Inc(labels[i%len(labels)])
+			i++
+		}
+	})
+}
@@ -0,0 +1,284 @@
+package gounter
+
+import (
+	"encoding"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// snapshotMagic starts every snapshot.
+const snapshotMagic = "GSNP"
+
+// kindRegistry is the kind of the binary encoding of Registry.
+const kindRegistry byte = 'r'
+
+// Snapshotter is implemented by Registry and LabelCounter.
+type Snapshotter interface {
+	SaveSnapshot(w io.Writer) error
+	LoadSnapshot(r io.Reader) error
+}
+
+// SnapshotError is returned when a snapshot is truncated or corrupt.
+// It unwraps to ErrCorruptSnapshot.
+type SnapshotError struct {
+	Reason string
+}
+
+// Error implements error.
+func (e *SnapshotError) Error() string {
+	return "corrupt snapshot: " + e.Reason
+}
+
+// Unwrap returns ErrCorruptSnapshot.
+func (e *SnapshotError) Unwrap() error {
+	return ErrCorruptSnapshot
+}
+
+// writeSnapshot writes the payload as
+// magic, uvarint length, payload and CRC32 of the payload.
+func writeSnapshot(w io.Writer, payload []byte) error {
+	b := append([]byte(snapshotMagic), appendUvarint(nil, uint64(len(payload)))...)
+	b = append(b, payload...)
+
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], crc32.ChecksumIEEE(payload))
+	b = append(b, sum[:]...)
+
+	_, err := w.Write(b)
+	return err
+}
+
+// readSnapshot reads a snapshot written by writeSnapshot, and returns the payload.
+func readSnapshot(r io.Reader) ([]byte, error) {
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, snapshotReadError(err, "missing header")
+	}
+	if string(magic) != snapshotMagic {
+		return nil, &SnapshotError{Reason: "bad magic"}
+	}
+
+	n, err := binary.ReadUvarint(byteReader{r})
+	if err != nil {
+		return nil, snapshotReadError(err, "missing length")
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(r, int64(n)))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(payload)) != n {
+		return nil, &SnapshotError{Reason: "truncated"}
+	}
+
+	var sum [4]byte
+	if _, err := io.ReadFull(r, sum[:]); err != nil {
+		return nil, snapshotReadError(err, "missing checksum")
+	}
+	if binary.LittleEndian.Uint32(sum[:]) != crc32.ChecksumIEEE(payload) {
+		return nil, &SnapshotError{Reason: "checksum mismatch"}
+	}
+
+	return payload, nil
+}
+
+// snapshotReadError returns a SnapshotError for EOF, or err.
+func snapshotReadError(err error, reason string) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return &SnapshotError{Reason: reason}
+	}
+
+	return err
+}
+
+// byteReader reads bytes one by one, for binary.ReadUvarint.
+type byteReader struct {
+	io.Reader
+}
+
+// ReadByte implements io.ByteReader.
+func (r byteReader) ReadByte() (byte, error) {
+	var b [1]byte
+	_, err := io.ReadFull(r.Reader, b[:])
+
+	return b[0], err
+}
+
+// SaveSnapshot writes all labels of the LabelCounter as a snapshot.
+func (counter *LabelCounter[T]) SaveSnapshot(w io.Writer) error {
+	data, err := counter.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	return writeSnapshot(w, data)
+}
+
+// LoadSnapshot replaces all labels of the LabelCounter with the snapshot.
+// A *SnapshotError is returned if the snapshot is truncated or corrupt.
+func (counter *LabelCounter[T]) LoadSnapshot(r io.Reader) error {
+	data, err := readSnapshot(r)
+	if err != nil {
+		return err
+	}
+
+	return counter.UnmarshalBinary(data)
+}
+
+// SaveSnapshot writes the registered metrics as a snapshot.
+// It returns ErrUnsupportedCounter if a metric does not implement encoding.BinaryMarshaler.
+func (r *Registry) SaveSnapshot(w io.Writer) error {
+	var body []byte
+	metrics := r.sortedMetrics()
+	for _, m := range metrics {
+		marshaler, ok := m.value.(encoding.BinaryMarshaler)
+		if !ok {
+			return ErrUnsupportedCounter
+		}
+
+		data, err := marshaler.MarshalBinary()
+		if err != nil {
+			return err
+		}
+
+		body = appendBytes(body, []byte(m.desc.Name))
+		body = appendBytes(body, data)
+	}
+
+	b := []byte{marshalVersion, kindRegistry}
+	b = appendUvarint(b, uint64(len(metrics)))
+
+	return writeSnapshot(w, append(b, body...))
+}
+
+// LoadSnapshot restores the registered metrics from the snapshot.
+// Metrics must be registered before, metrics in the snapshot
+// but not registered are ignored.
+// It returns ErrUnsupportedCounter if a metric does not implement encoding.BinaryUnmarshaler.
+// A *SnapshotError is returned if the snapshot is truncated or corrupt.
+//
+// Metrics of gounter are decoded before any of them is changed,
+// so nothing is changed on a bad snapshot. Only a LabelCounter closed or limited
+// at the same time can fail after some metrics are changed.
+// Other encoding.BinaryUnmarshaler are unmarshaled after them.
+func (r *Registry) LoadSnapshot(rd io.Reader) error {
+	data, err := readSnapshot(rd)
+	if err != nil {
+		return err
+	}
+
+	d := newDecoder(data, kindRegistry)
+	n := d.uvarint()
+
+	var names []string
+	var values [][]byte
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		names = append(names, string(d.bytes()))
+		values = append(values, d.bytes())
+	}
+	if err := d.done(); err != nil {
+		return err
+	}
+
+	var decodes []decoded
+	var others []func() error
+	for i, name := range names {
+		c, ok := r.Get(name)
+		if !ok {
+			continue
+		}
+
+		var dec decoded
+		switch u := c.(type) {
+		case binaryDecoder:
+			dec, err = u.decodeBinary(values[i])
+		case encoding.BinaryUnmarshaler:
+			// not checked before apply, applied after all others
+			data := values[i]
+			others = append(others, func() error {
+				return u.UnmarshalBinary(data)
+			})
+			continue
+		default:
+			err = ErrUnsupportedCounter
+		}
+		if err != nil {
+			discardAll(decodes)
+			return err
+		}
+
+		decodes = append(decodes, dec)
+	}
+
+	for i, dec := range decodes {
+		if err := dec.apply(); err != nil {
+			discardAll(decodes[i+1:])
+			return err
+		}
+	}
+
+	for _, unmarshal := range others {
+		if err := unmarshal(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SaveSnapshotFile writes the snapshot to the file atomically.
+// It writes to a temp file in the same directory, syncs and renames it,
+// so the file is either the old or the new snapshot.
+func SaveSnapshotFile(path string, s Snapshotter) (err error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	f, err := os.CreateTemp(dir, base+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if err = s.SaveSnapshot(f); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+
+	// sync the directory for the rename, not supported everywhere
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
+
+// LoadSnapshotFile reads the snapshot from the file.
+func LoadSnapshotFile(path string, s Snapshotter) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return s.LoadSnapshot(f)
+}
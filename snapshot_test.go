@@ -0,0 +1,316 @@
+package gounter
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLabelCounterSnapshot(t *testing.T) {
+	t.Parallel()
+
+	c := NewLabelCounterNormal()
+	c.Add("a", 1)
+	c.Add("b", 2.5)
+
+	buf := &bytes.Buffer{}
+	if err := c.SaveSnapshot(buf); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+
+	got := NewLabelCounterNormal()
+	got.Add("old", 1)
+	if err := got.LoadSnapshot(buf); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+	testLabelSnapshot(t, got.Snapshot(), map[string]float64{"a": 1, "b": 2.5})
+}
+
+func TestRegistrySnapshot(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+	requests, _ := r.Counter(MetricDesc{Name: "requests_total"})
+	requests.Add(10)
+	conns, _ := r.MaxCounter(MetricDesc{Name: "connections", Type: MetricGauge}, 5)
+	conns.Add(3)
+	codes, _ := r.LabelCounter(MetricDesc{Name: "codes_total"})
+	codes.Add("200", 7)
+	r.Register(MetricDesc{Name: "sharded_total"}, AcquireShardedCounter())
+	r.Counter(MetricDesc{Name: "removed_total"})
+
+	buf := &bytes.Buffer{}
+	if err := r.SaveSnapshot(buf); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+
+	// after restart
+	restored := NewRegistry()
+	requests, _ = restored.Counter(MetricDesc{Name: "requests_total"})
+	conns, _ = restored.MaxCounter(MetricDesc{Name: "connections", Type: MetricGauge}, 1)
+	codes, _ = restored.LabelCounter(MetricDesc{Name: "codes_total"})
+	if err := restored.LoadSnapshot(buf); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+
+	if v := requests.Get(); v != 10 {
+		t.Errorf("should be 10, but %f", v)
+	}
+	if v, max := conns.Get(), conns.GetMax(); v != 3 || max != 5 {
+		t.Errorf("should be 3 and 5, but %f and %f", v, max)
+	}
+	testLabelSnapshot(t, codes.Snapshot(), map[string]float64{"200": 7})
+}
+
+func TestRegistryLoadSnapshotError(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+	a, _ := r.Counter(MetricDesc{Name: "a_total"})
+	a.Add(1)
+	codes, _ := r.LabelCounter(MetricDesc{Name: "b_total"})
+	codes.Add("200", 2)
+	r.MaxCounter(MetricDesc{Name: "c", Type: MetricGauge}, 5)
+
+	buf := &bytes.Buffer{}
+	if err := r.SaveSnapshot(buf); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+
+	// c is registered as another kind, nothing is restored
+	restored := NewRegistry()
+	a, _ = restored.Counter(MetricDesc{Name: "a_total"})
+	a.Add(10)
+	acquired, released := 0, 0
+	codes = NewLabelCounter[*Counter](func() *Counter {
+		acquired++
+		return AcquireCounter()
+	}, func(c *Counter) {
+		released++
+		ReleaseCounter(c)
+	})
+	restored.Register(MetricDesc{Name: "b_total"}, codes)
+	codes.Add("keep", 3)
+	restored.Counter(MetricDesc{Name: "c"})
+	if err := restored.LoadSnapshot(bytes.NewReader(buf.Bytes())); err != ErrInvalidEncoding {
+		t.Fatalf("should be %v, but %v", ErrInvalidEncoding, err)
+	}
+
+	if v := a.Get(); v != 10 {
+		t.Errorf("should be 10, but %f", v)
+	}
+	testLabelSnapshot(t, codes.Snapshot(), map[string]float64{"keep": 3})
+
+	// the decoded counters of b are released
+	if acquired != 2 || released != 1 {
+		t.Errorf("should be 2 and 1, but %d and %d", acquired, released)
+	}
+
+	// not registered
+	restored.Unregister("c")
+	restored.Register(MetricDesc{Name: "c", Type: MetricGauge}, valueGounter{AcquireCounter()})
+	if err := restored.LoadSnapshot(bytes.NewReader(buf.Bytes())); err != ErrUnsupportedCounter {
+		t.Fatalf("should be %v, but %v", ErrUnsupportedCounter, err)
+	}
+	if err := restored.SaveSnapshot(&bytes.Buffer{}); err != ErrUnsupportedCounter {
+		t.Fatalf("should be %v, but %v", ErrUnsupportedCounter, err)
+	}
+	if v := a.Get(); v != 10 {
+		t.Errorf("should be 10, but %f", v)
+	}
+}
+
+func TestRegistrySnapshotTypes(t *testing.T) {
+	t.Parallel()
+
+	exact := int64(1)<<53 + 1
+
+	r := NewRegistry()
+	i := &Int64Counter{}
+	i.SetInt(exact)
+	sharded := AcquireShardedCounter()
+	sharded.Add(2)
+	precise := &PreciseCounter{}
+	precise.Add(0.1)
+	typed := NewTypedCounter[int64]()
+	typed.Set(-3)
+	observed := Observe(AcquireCounter())
+	observed.Add(4)
+	labels := NewShardedLabelCounterNormal(4)
+	labels.Add("a", 5)
+	labels.Add("b", 6)
+
+	for name, c := range map[string]any{
+		"int_total":      i,
+		"sharded_total":  sharded,
+		"precise_total":  precise,
+		"typed_total":    AsGounter[int64](typed),
+		"observed_total": observed,
+		"labels_total":   labels,
+	} {
+		if err := r.Register(MetricDesc{Name: name}, c); err != nil {
+			t.Fatalf("should be nil, but %v", err)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := r.SaveSnapshot(buf); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+
+	restored := NewRegistry()
+	i = &Int64Counter{}
+	sharded = AcquireShardedCounter()
+	precise = &PreciseCounter{}
+	typed = NewTypedCounter[int64]()
+	changes := &changeObserver{}
+	observed = Observe(AcquireCounter(), changes)
+	// in other shards
+	labels = NewShardedLabelCounterNormal(7)
+	labels.Add("old", 1)
+	for name, c := range map[string]any{
+		"int_total":      i,
+		"sharded_total":  sharded,
+		"precise_total":  precise,
+		"typed_total":    AsGounter[int64](typed),
+		"observed_total": observed,
+		"labels_total":   labels,
+	} {
+		restored.Register(MetricDesc{Name: name}, c)
+	}
+	if err := restored.LoadSnapshot(buf); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+
+	if i.RealInt() != exact || sharded.Real() != 2 || precise.Real() != 0.1 || typed.Real() != -3 || observed.Real() != 4 {
+		t.Errorf("wrong state, got %d %f %f %d %f", i.RealInt(), sharded.Real(), precise.Real(), typed.Real(), observed.Real())
+	}
+	if changes.n != 1 {
+		t.Errorf("should be notified %d, but %d", 1, changes.n)
+	}
+	testLabelSnapshot(t, labels.Snapshot(), map[string]float64{"a": 5, "b": 6})
+}
+
+// changeObserver counts changes.
+type changeObserver struct {
+	NopObserver
+	n int
+}
+
+func (o *changeObserver) OnChange(old, new float64) {
+	o.n++
+}
+
+func TestLoadSnapshotCorrupt(t *testing.T) {
+	t.Parallel()
+
+	c := NewLabelCounterNormal()
+	c.Add("a", 1)
+
+	buf := &bytes.Buffer{}
+	c.SaveSnapshot(buf)
+	data := buf.Bytes()
+
+	flipped := append([]byte(nil), data...)
+	flipped[len(flipped)-6] ^= 0xff
+
+	badMagic := append([]byte(nil), data...)
+	badMagic[0] = 'X'
+
+	tests := map[string][]byte{
+		"empty":       nil,
+		"header":      data[:2],
+		"length":      data[:len(snapshotMagic)],
+		"payload":     data[:len(data)-8],
+		"checksum":    data[:len(data)-2],
+		"flipped":     flipped,
+		"magic":       badMagic,
+		"huge length": append([]byte(snapshotMagic), 0xff, 0xff, 0xff, 0xff, 0x0f),
+	}
+	for name, data := range tests {
+		got := NewLabelCounterNormal()
+		got.Add("keep", 1)
+
+		err := got.LoadSnapshot(bytes.NewReader(data))
+		var snapshotErr *SnapshotError
+		if !errors.As(err, &snapshotErr) || !errors.Is(err, ErrCorruptSnapshot) {
+			t.Errorf("%s, should be SnapshotError, but %v", name, err)
+		}
+		testLabelSnapshot(t, got.Snapshot(), map[string]float64{"keep": 1})
+	}
+}
+
+func TestSnapshotFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "counters.snap")
+
+	c := NewLabelCounterNormal()
+	c.Add("a", 1)
+	if err := SaveSnapshotFile(path, c); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+
+	// overwrite
+	c.Add("b", 2)
+	if err := SaveSnapshotFile(path, c); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("temp files left, got %v", entries)
+	}
+
+	got := NewLabelCounterNormal()
+	if err := LoadSnapshotFile(path, got); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+	testLabelSnapshot(t, got.Snapshot(), map[string]float64{"a": 1, "b": 2})
+
+	// truncated file
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, data[:len(data)-3], 0o600)
+	if err := LoadSnapshotFile(path, got); !errors.Is(err, ErrCorruptSnapshot) {
+		t.Fatalf("should be %v, but %v", ErrCorruptSnapshot, err)
+	}
+
+	if err := LoadSnapshotFile(filepath.Join(dir, "missing"), got); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("should be %v, but %v", os.ErrNotExist, err)
+	}
+}
+
+func TestSaveSnapshotFileError(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "counters.snap")
+	os.WriteFile(path, []byte("old"), 0o600)
+
+	// a failed save keeps the old file
+	r := NewRegistry()
+	r.Register(MetricDesc{Name: "x_total"}, failMarshaler{AcquireCounter()})
+	if err := SaveSnapshotFile(path, r); err != errTestMarshal {
+		t.Fatalf("should be %v, but %v", errTestMarshal, err)
+	}
+
+	data, _ := os.ReadFile(path)
+	entries, _ := os.ReadDir(dir)
+	if string(data) != "old" || len(entries) != 1 {
+		t.Fatalf("should keep the old file, got %q %v", data, entries)
+	}
+}
+
+var errTestMarshal = errors.New("marshal failed")
+
+// failMarshaler is a Gounter failing MarshalBinary.
+type failMarshaler struct {
+	*Counter
+}
+
+func (failMarshaler) MarshalBinary() ([]byte, error) {
+	return nil, errTestMarshal
+}
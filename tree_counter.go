@@ -0,0 +1,129 @@
+package gounter
+
+import "strings"
+
+// defaultTreeSeparator is the separator of TreeCounter when it is not given.
+const defaultTreeSeparator = "."
+
+// TreeCounter is a hierarchical LabelCounter.
+// Changing `a.b.c` also changes the aggregates at `a.b` and `a`,
+// so reading any node is O(1), and updating is O(depth).
+//
+// The nodes of a path are updated one by one,
+// concurrent readers may see a node updated before its ancestors.
+type TreeCounter struct {
+	noCopy noCopy
+
+	sep     string
+	counter *LabelCounter[*Counter]
+}
+
+// NewTreeCounter creates and returns a new TreeCounter with the separator.
+// If sep is empty, "." is used.
+func NewTreeCounter(sep string) *TreeCounter {
+	if sep == "" {
+		sep = defaultTreeSeparator
+	}
+
+	return &TreeCounter{
+		sep:     sep,
+		counter: NewLabelCounterNormal(),
+	}
+}
+
+// path returns the node and all its ancestors, from the root.
+func (tree *TreeCounter) path(node string) []string {
+	nodes := make([]string, 0, strings.Count(node, tree.sep)+1)
+	for i := 0; ; {
+		j := strings.Index(node[i:], tree.sep)
+		if j < 0 {
+			break
+		}
+
+		i += j
+		nodes = append(nodes, node[:i])
+		i += len(tree.sep)
+	}
+
+	return append(nodes, node)
+}
+
+// Add adds the delta to the node and all its ancestors.
+// TreeCounter always returns true.
+func (tree *TreeCounter) Add(node string, delta float64) bool {
+	for _, n := range tree.path(node) {
+		tree.counter.Add(n, delta)
+	}
+
+	return true
+}
+
+// Sub subtracts the delta from the node and all its ancestors.
+// TreeCounter always returns true.
+func (tree *TreeCounter) Sub(node string, delta float64) bool {
+	return tree.Add(node, delta*-1)
+}
+
+// Inc increases the node and all its ancestors by 1.
+// TreeCounter always returns true.
+func (tree *TreeCounter) Inc(node string) bool {
+	return tree.Add(node, 1)
+}
+
+// Dec decreases the node and all its ancestors by 1.
+// TreeCounter always returns true.
+func (tree *TreeCounter) Dec(node string) bool {
+	return tree.Add(node, -1)
+}
+
+// Get returns the aggregate of the node,
+// which is the sum of the node and all its descendants.
+func (tree *TreeCounter) Get(node string) float64 {
+	v, _ := tree.counter.Get(node)
+	return v
+}
+
+// Children returns the direct children of the node and their aggregates,
+// sorted by node. The children of "" are the roots.
+func (tree *TreeCounter) Children(node string) []LabelValue {
+	prefix := ""
+	if node != "" {
+		prefix = node + tree.sep
+	}
+
+	children := make([]LabelValue, 0)
+	for _, value := range tree.counter.Prefix(prefix) {
+		if !strings.Contains(value.Label[len(prefix):], tree.sep) {
+			children = append(children, value)
+		}
+	}
+
+	return children
+}
+
+// Remove removes the node and all its descendants,
+// and subtracts its aggregate from its ancestors.
+func (tree *TreeCounter) Remove(node string) {
+	_, c := tree.counter.Get(node)
+	if c == nil {
+		return
+	}
+
+	// Get clamps negative values to 0
+	v := c.Real()
+
+	path := tree.path(node)
+	for _, n := range path[:len(path)-1] {
+		tree.counter.Sub(n, v)
+	}
+
+	for _, value := range tree.counter.Prefix(node + tree.sep) {
+		tree.counter.RemoveLabel(value.Label)
+	}
+	tree.counter.RemoveLabel(node)
+}
+
+// Reset resets the TreeCounter to an empty state.
+func (tree *TreeCounter) Reset() {
+	tree.counter.Reset()
+}
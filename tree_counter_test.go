@@ -0,0 +1,100 @@
+package gounter
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestTreeCounter(t *testing.T) {
+	t.Parallel()
+
+	c := NewTreeCounter("")
+
+	c.Inc("a.b.c")
+	c.Add("a.b.d", 2)
+	c.Add("a.e", 4)
+	c.Inc("a")
+	c.Add("f", 8)
+
+	tests := map[string]float64{
+		"a.b.c": 1,
+		"a.b.d": 2,
+		"a.b":   3,
+		"a.e":   4,
+		"a":     8,
+		"f":     8,
+		"g":     0,
+	}
+	for node, want := range tests {
+		if v := c.Get(node); v != want {
+			t.Errorf("node %s, wrong result, expect %f, got %f", node, want, v)
+		}
+	}
+
+	roots := c.Children("")
+	if len(roots) != 2 || roots[0] != (LabelValue{"a", 8}) || roots[1] != (LabelValue{"f", 8}) {
+		t.Errorf("wrong result, got %v", roots)
+	}
+
+	children := c.Children("a")
+	if len(children) != 2 || children[0] != (LabelValue{"a.b", 3}) || children[1] != (LabelValue{"a.e", 4}) {
+		t.Errorf("wrong result, got %v", children)
+	}
+
+	c.Dec("a.b.d")
+	if v := c.Get("a"); v != 7 {
+		t.Errorf("wrong result, expect %d, got %f", 7, v)
+	}
+
+	c.Remove("a.b")
+	if v := c.Get("a"); v != 5 {
+		t.Errorf("wrong result, expect %d, got %f", 5, v)
+	}
+	if v := c.Get("a.b.c"); v != 0 {
+		t.Errorf("wrong result, expect %d, got %f", 0, v)
+	}
+	if n := len(c.Children("a")); n != 1 {
+		t.Errorf("wrong result, expect %d, got %d", 1, n)
+	}
+
+	c.Reset()
+	if v := c.Get("f"); v != 0 {
+		t.Errorf("wrong result, expect %d, got %f", 0, v)
+	}
+}
+
+func TestTreeCounterRemoveNegative(t *testing.T) {
+	t.Parallel()
+
+	c := NewTreeCounter("")
+	c.Sub("a.b", 2)
+	c.Add("a.c", 5)
+	c.Remove("a.b")
+
+	if v := c.Get("a"); v != 5 {
+		t.Errorf("wrong result, expect %d, got %f", 5, v)
+	}
+}
+
+func TestTreeCounterSeparator(t *testing.T) {
+	t.Parallel()
+
+	c := NewTreeCounter("::")
+
+	wg := sync.WaitGroup{}
+	wg.Add(100)
+	for i := 0; i < 100; i++ {
+		go func() {
+			c.Inc("svc::api::users")
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+
+	if v := c.Get("svc"); v != 100 {
+		t.Errorf("wrong result, expect %d, got %f", 100, v)
+	}
+	if v := c.Get("svc::api"); v != 100 {
+		t.Errorf("wrong result, expect %d, got %f", 100, v)
+	}
+}
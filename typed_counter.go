@@ -0,0 +1,263 @@
+package gounter
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// Number is the constraint of TypedCounter value.
+type Number interface {
+	int32 | int64 | uint64 | float64
+}
+
+// TypedGounter is the generic version of Gounter.
+type TypedGounter[N Number] interface {
+	Get() N
+	Reset()
+
+	Set(N) bool
+	Add(N) bool
+	Sub(N) bool
+	Inc() bool
+	Dec() bool
+
+	CopyTo(interface{}) (bool, error)
+}
+
+// TypedCounter is a generic Counter over integer and float types.
+// The value is stored as uint64 bits and updated by CAS.
+//
+// Integer types wrap around on overflow.
+// Unsigned counter rejects decreasing below 0.
+// Like Counter, `Get()` returns 0 when the value is negative.
+//
+// Copying is prohibited. Please use NewTypedCounter.
+type TypedCounter[N Number] struct {
+	noCopy noCopy
+
+	bits uint64
+}
+
+// NewTypedCounter returns a new TypedCounter.
+func NewTypedCounter[N Number]() *TypedCounter[N] {
+	return &TypedCounter[N]{}
+}
+
+// typedToBits converts N to uint64 bits.
+func typedToBits[N Number](n N) uint64 {
+	switch v := any(n).(type) {
+	case int32:
+		return uint64(uint32(v))
+	case int64:
+		return uint64(v)
+	case uint64:
+		return v
+	case float64:
+		return math.Float64bits(v)
+	}
+
+	return 0
+}
+
+// typedFromBits converts uint64 bits to N.
+func typedFromBits[N Number](bits uint64) N {
+	var n N
+	switch any(n).(type) {
+	case int32:
+		return N(int32(uint32(bits)))
+	case int64:
+		return N(int64(bits))
+	case uint64:
+		return N(bits)
+	case float64:
+		return N(math.Float64frombits(bits))
+	}
+
+	return n
+}
+
+// typedUnsigned say N is unsigned?
+func typedUnsigned[N Number]() bool {
+	var zero N
+	return zero-1 > 0
+}
+
+// reset TypedCounter.
+func (c *TypedCounter[N]) reset() {
+	atomic.StoreUint64(&c.bits, 0)
+}
+
+// Get returns a number.
+// When the counter value is negative, it returns 0.
+func (c *TypedCounter[N]) Get() N {
+	val := c.Real()
+	if val < 0 {
+		return 0
+	}
+
+	return val
+}
+
+// Real returns a number in counter.
+func (c *TypedCounter[N]) Real() N {
+	return typedFromBits[N](atomic.LoadUint64(&c.bits))
+}
+
+// Set sets the value of the counter.
+// TypedCounter always returns true.
+func (c *TypedCounter[N]) Set(value N) bool {
+	atomic.StoreUint64(&c.bits, typedToBits(value))
+	return true
+}
+
+// Add increases the counter number.
+// Decreasing use Sub, or negative number for signed types.
+// TypedCounter always returns true.
+func (c *TypedCounter[N]) Add(delta N) bool {
+	for {
+		oldBits := atomic.LoadUint64(&c.bits)
+		newBits := typedToBits(typedFromBits[N](oldBits) + delta)
+		if atomic.CompareAndSwapUint64(&c.bits, oldBits, newBits) {
+			return true
+		}
+	}
+}
+
+// Sub decreases the counter number.
+// For unsigned types, it returns false when the result would be below 0.
+func (c *TypedCounter[N]) Sub(delta N) bool {
+	unsigned := typedUnsigned[N]()
+	for {
+		oldBits := atomic.LoadUint64(&c.bits)
+		oldVal := typedFromBits[N](oldBits)
+		if unsigned && oldVal < delta {
+			return false
+		}
+
+		newBits := typedToBits(oldVal - delta)
+		if atomic.CompareAndSwapUint64(&c.bits, oldBits, newBits) {
+			return true
+		}
+	}
+}
+
+// Inc increases the counter by 1.
+func (c *TypedCounter[N]) Inc() bool {
+	return c.Add(1)
+}
+
+// Dec decreases the counter by 1.
+func (c *TypedCounter[N]) Dec() bool {
+	return c.Sub(1)
+}
+
+// Reset resets this TypedCounter.
+func (c *TypedCounter[N]) Reset() {
+	c.reset()
+}
+
+// CopyTo copies a TypedCounter to other TypedCounter with the same N.
+func (c *TypedCounter[N]) CopyTo(d interface{}) (ok bool, err error) {
+	dst, can := d.(*TypedCounter[N])
+	if !can {
+		err = ErrDifferentCounterType
+		return
+	}
+
+	if c == dst {
+		err = ErrSameCounterPointer
+		return
+	}
+
+	atomic.StoreUint64(&dst.bits, atomic.LoadUint64(&c.bits))
+	ok = true
+	return
+}
+
+// GounterAdapter exposes a TypedGounter as a float64 Gounter,
+// so it can be used in LabelCounter and by existing callers.
+// Values are converted with Go conversion rules.
+type GounterAdapter[N Number] struct {
+	counter TypedGounter[N]
+}
+
+// AsGounter returns a float64 Gounter view of the TypedGounter.
+func AsGounter[N Number](c TypedGounter[N]) *GounterAdapter[N] {
+	return &GounterAdapter[N]{counter: c}
+}
+
+// Unwrap returns the underlying TypedGounter.
+func (a *GounterAdapter[N]) Unwrap() TypedGounter[N] {
+	return a.counter
+}
+
+// Get returns the value as float64.
+func (a *GounterAdapter[N]) Get() float64 {
+	return float64(a.counter.Get())
+}
+
+// Real returns the real value as float64,
+// or Get if the TypedGounter has no Real.
+func (a *GounterAdapter[N]) Real() float64 {
+	if r, ok := a.counter.(interface{ Real() N }); ok {
+		return float64(r.Real())
+	}
+
+	return a.Get()
+}
+
+// Reset resets the underlying TypedGounter.
+func (a *GounterAdapter[N]) Reset() {
+	a.counter.Reset()
+}
+
+// Set sets the value converted to N.
+func (a *GounterAdapter[N]) Set(value float64) bool {
+	return a.counter.Set(N(value))
+}
+
+// Add adds the delta converted to N.
+// Negative delta is passed to Sub.
+func (a *GounterAdapter[N]) Add(delta float64) bool {
+	if delta < 0 {
+		return a.counter.Sub(N(-delta))
+	}
+
+	return a.counter.Add(N(delta))
+}
+
+// Sub subtracts the delta converted to N.
+// Negative delta is passed to Add.
+func (a *GounterAdapter[N]) Sub(delta float64) bool {
+	if delta < 0 {
+		return a.counter.Add(N(-delta))
+	}
+
+	return a.counter.Sub(N(delta))
+}
+
+// Inc increases the underlying counter by 1.
+func (a *GounterAdapter[N]) Inc() bool {
+	return a.counter.Inc()
+}
+
+// Dec decreases the underlying counter by 1.
+func (a *GounterAdapter[N]) Dec() bool {
+	return a.counter.Dec()
+}
+
+// CopyTo copies the underlying counter to other GounterAdapter.
+func (a *GounterAdapter[N]) CopyTo(d interface{}) (ok bool, err error) {
+	dst, can := d.(*GounterAdapter[N])
+	if !can {
+		err = ErrDifferentCounterType
+		return
+	}
+
+	if a == dst {
+		err = ErrSameCounterPointer
+		return
+	}
+
+	return a.counter.CopyTo(dst.counter)
+}
@@ -0,0 +1,139 @@
+package gounter
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestTypedCounterChange(t *testing.T) {
+	t.Parallel()
+
+	testTypedCounterIncAndDec[int32](t)
+	testTypedCounterIncAndDec[int64](t)
+	testTypedCounterIncAndDec[uint64](t)
+	testTypedCounterIncAndDec[float64](t)
+
+	testGo(t, testTypedCounterIncAndDec[int64], 10)
+	testGo(t, testTypedCounterIncAndDec[uint64], 10)
+}
+
+func TestTypedCounterInt32(t *testing.T) {
+	t.Parallel()
+
+	c := NewTypedCounter[int32]()
+	c.Sub(5)
+	if v := c.Real(); v != -5 {
+		t.Fatalf("should be %d, but %d", -5, v)
+	}
+	if v := c.Get(); v != 0 {
+		t.Fatalf("should be %d, but %d", 0, v)
+	}
+
+	c.Set(1 << 30)
+	c.Add(1)
+	if v := c.Get(); v != 1<<30+1 {
+		t.Fatalf("should be %d, but %d", 1<<30+1, v)
+	}
+}
+
+func TestTypedCounterUnsignedSub(t *testing.T) {
+	t.Parallel()
+
+	c := NewTypedCounter[uint64]()
+	c.Add(3)
+
+	if ok := c.Sub(4); ok {
+		t.Fatal("should be false, but true")
+	}
+	if ok := c.Sub(3); !ok {
+		t.Fatal("should be true, but false")
+	}
+	if ok := c.Dec(); ok {
+		t.Fatal("should be false, but true")
+	}
+	if v := c.Get(); v != 0 {
+		t.Fatalf("should be %d, but %d", 0, v)
+	}
+}
+
+func TestTypedCounterCopyTo(t *testing.T) {
+	t.Parallel()
+
+	c1 := NewTypedCounter[int64]()
+	c2 := NewTypedCounter[int64]()
+	c1.Add(9)
+
+	ok, err := c1.CopyTo(c1)
+	if ok || err != ErrSameCounterPointer {
+		t.Fatalf("same counter should err, but %v", err)
+	}
+
+	ok, err = c1.CopyTo(NewTypedCounter[int32]())
+	if ok || err != ErrDifferentCounterType {
+		t.Fatalf("different counter should err, but %v", err)
+	}
+
+	ok, err = c1.CopyTo(c2)
+	if !ok || err != nil {
+		t.Fatalf("counter should be copied, but err: %v", err)
+	}
+	if v := c2.Get(); v != 9 {
+		t.Fatalf("should be %d, but %d", 9, v)
+	}
+}
+
+func TestGounterAdapter(t *testing.T) {
+	t.Parallel()
+
+	var g Gounter = AsGounter[uint64](NewTypedCounter[uint64]())
+
+	g.Add(10)
+	g.Add(-4)
+	g.Sub(-1)
+	if v := g.Get(); v != 7 {
+		t.Fatalf("should be %d, but %f", 7, v)
+	}
+
+	if ok := g.Sub(8); ok {
+		t.Fatal("should be false, but true")
+	}
+
+	g2 := AsGounter[uint64](NewTypedCounter[uint64]())
+	ok, err := g.CopyTo(g2)
+	if !ok || err != nil {
+		t.Fatalf("counter should be copied, but err: %v", err)
+	}
+	if v := g2.Unwrap().Get(); v != 7 {
+		t.Fatalf("should be %d, but %d", 7, v)
+	}
+}
+
+func TestLabelCounterWithTyped(t *testing.T) {
+	t.Parallel()
+
+	labels := testGenerateLabels()
+
+	c := NewLabelCounterTyped[int64]()
+	testLabelCounterIncDec(labels, 1000, c, t)
+}
+
+func testTypedCounterIncAndDec[N Number](t *testing.T) {
+	c := NewTypedCounter[N]()
+
+	wg := sync.WaitGroup{}
+	wg.Add(100)
+	for i := 0; i < 100; i++ {
+		go func() {
+			c.Inc()
+			c.Inc()
+			c.Dec()
+			wg.Done()
+		}()
+	}
+
+	wg.Wait()
+
+	if v := c.Get(); v != 100 {
+		t.Fatalf("should be %d, but %v", 100, v)
+	}
+}
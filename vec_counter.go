@@ -0,0 +1,291 @@
+package gounter
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// VecCounter is a LabelCounter with multi-dimensional labels.
+// Dimensions are declared when it is created,
+// and each counter is keyed by one value per dimension.
+// Values are stored canonically in the order of the dimensions.
+type VecCounter[T Gounter] struct {
+	noCopy noCopy
+
+	dims    []string
+	index   map[string]int
+	counter *LabelCounter[T]
+}
+
+// VecEntry is the values of dimensions and the value of a counter.
+type VecEntry struct {
+	Values []string
+	Value  float64
+}
+
+// NewVecCounter creates and returns a new VecCounter with the dimension names.
+// It returns ErrInvalidDimension if a name is empty or duplicate.
+func NewVecCounter[T Gounter](dims []string, acq func() T, rel func(T)) (*VecCounter[T], error) {
+	index := make(map[string]int, len(dims))
+	for i, dim := range dims {
+		if _, ok := index[dim]; ok || dim == "" {
+			return nil, ErrInvalidDimension
+		}
+		index[dim] = i
+	}
+
+	return &VecCounter[T]{
+		dims:    append([]string(nil), dims...),
+		index:   index,
+		counter: NewLabelCounter[T](acq, rel),
+	}, nil
+}
+
+// NewVecCounterNormal returns a new VecCounter with Counter as the underlying type.
+func NewVecCounterNormal(dims ...string) (*VecCounter[*Counter], error) {
+	return NewVecCounter[*Counter](dims, AcquireCounter, ReleaseCounter)
+}
+
+// vecKey encodes values to the key of LabelCounter,
+// each value is prefixed with its length.
+func vecKey(values []string) string {
+	n := 0
+	for _, v := range values {
+		n += binary.MaxVarintLen64 + len(v)
+	}
+
+	buf := make([]byte, 0, n)
+	tmp := make([]byte, binary.MaxVarintLen64)
+	for _, v := range values {
+		l := binary.PutUvarint(tmp, uint64(len(v)))
+		buf = append(buf, tmp[:l]...)
+		buf = append(buf, v...)
+	}
+
+	return string(buf)
+}
+
+// vecValues decodes the key of LabelCounter to values.
+func vecValues(key string, n int) []string {
+	values := make([]string, n)
+	buf := []byte(key)
+	for i := 0; i < n && len(buf) > 0; i++ {
+		l, size := binary.Uvarint(buf)
+		if size <= 0 || l > uint64(len(buf)-size) {
+			// malformed, keep the rest empty
+			break
+		}
+
+		buf = buf[size:]
+		values[i] = string(buf[:l])
+		buf = buf[l:]
+	}
+
+	return values
+}
+
+// Dims returns the dimension names.
+func (vec *VecCounter[T]) Dims() []string {
+	return append([]string(nil), vec.dims...)
+}
+
+// valuesOf converts the map to values in the order of dimensions.
+func (vec *VecCounter[T]) valuesOf(labels map[string]string) ([]string, error) {
+	if len(labels) != len(vec.dims) {
+		return nil, ErrLabelValuesCount
+	}
+
+	values := make([]string, len(vec.dims))
+	for dim, v := range labels {
+		i, ok := vec.index[dim]
+		if !ok {
+			return nil, ErrUnknownDimension
+		}
+		values[i] = v
+	}
+
+	return values, nil
+}
+
+// matcher converts the map to a function matching values.
+func (vec *VecCounter[T]) matcher(match map[string]string) (func([]string) bool, error) {
+	idx := make([]int, 0, len(match))
+	want := make([]string, 0, len(match))
+	for dim, v := range match {
+		i, ok := vec.index[dim]
+		if !ok {
+			return nil, ErrUnknownDimension
+		}
+		idx = append(idx, i)
+		want = append(want, v)
+	}
+
+	return func(values []string) bool {
+		for j, i := range idx {
+			if values[i] != want[j] {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// WithLabelValues returns the Gounter of the values, one per dimension in order.
+// It creates the Gounter if not exist.
+func (vec *VecCounter[T]) WithLabelValues(values ...string) (c T, err error) {
+	if len(values) != len(vec.dims) {
+		err = ErrLabelValuesCount
+		return
+	}
+
+	c, idx := vec.counter.getLabel(vecKey(values), false, true)
+	if idx == -1 {
+		err = ErrLabelLimit
+		if vec.counter.isClosed() {
+			err = ErrLabelCounterClosed
+		}
+	}
+	return
+}
+
+// With returns the Gounter of the dimension-value map.
+// It creates the Gounter if not exist.
+func (vec *VecCounter[T]) With(labels map[string]string) (c T, err error) {
+	values, err := vec.valuesOf(labels)
+	if err != nil {
+		return
+	}
+
+	return vec.WithLabelValues(values...)
+}
+
+// Remove removes the Gounter of the values.
+func (vec *VecCounter[T]) Remove(values ...string) error {
+	if len(values) != len(vec.dims) {
+		return ErrLabelValuesCount
+	}
+
+	vec.counter.RemoveLabel(vecKey(values))
+	return nil
+}
+
+// SetLabelLimit sets the maximum number of counters, see LabelCounter.SetLabelLimit.
+// With LabelLimitReject, WithLabelValues and With return ErrLabelLimit over the limit.
+// With LabelLimitOverflow, new counters are folded into the one
+// whose values are all OverflowLabel.
+func (vec *VecCounter[T]) SetLabelLimit(limit int, policy LabelLimitPolicy) {
+	overflow := make([]string, len(vec.dims))
+	for i := range overflow {
+		overflow[i] = OverflowLabel
+	}
+
+	vec.counter.SetOverflowLabel(vecKey(overflow))
+	vec.counter.SetLabelLimit(limit, policy)
+}
+
+// Reset resets the VecCounter to an empty state.
+func (vec *VecCounter[T]) Reset() {
+	vec.counter.Reset()
+}
+
+// Close releases all counters, see LabelCounter.Close.
+func (vec *VecCounter[T]) Close() error {
+	return vec.counter.Close()
+}
+
+// Len returns the number of counters.
+func (vec *VecCounter[T]) Len() int {
+	return vec.counter.Len()
+}
+
+// Range calls f for each counter with its values and Gounter,
+// until f returns false.
+// It holds the read lock, f must not add or remove counters of the VecCounter.
+func (vec *VecCounter[T]) Range(f func(values []string, v float64, c T) bool) {
+	vec.counter.Range(func(key string, v float64, c T) bool {
+		return f(vecValues(key, len(vec.dims)), v, c)
+	})
+}
+
+// Filter returns the counters matching all the given dimension values.
+// An empty match returns all counters.
+func (vec *VecCounter[T]) Filter(match map[string]string) ([]VecEntry, error) {
+	matched, err := vec.matcher(match)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]VecEntry, 0)
+	vec.Range(func(values []string, v float64, c T) bool {
+		if matched(values) {
+			entries = append(entries, VecEntry{Values: values, Value: v})
+		}
+		return true
+	})
+
+	return entries, nil
+}
+
+// Sum returns the sum of the counters matching all the given dimension values.
+func (vec *VecCounter[T]) Sum(match map[string]string) (float64, error) {
+	entries, err := vec.Filter(match)
+	if err != nil {
+		return 0, err
+	}
+
+	var sum float64
+	for _, entry := range entries {
+		sum += entry.Value
+	}
+
+	return sum, nil
+}
+
+// SumBy sums the counters grouped by the given dimensions,
+// Values of the returned entries are the values of these dimensions.
+// Entries are sorted by values.
+func (vec *VecCounter[T]) SumBy(dims ...string) ([]VecEntry, error) {
+	idx := make([]int, len(dims))
+	for j, dim := range dims {
+		i, ok := vec.index[dim]
+		if !ok {
+			return nil, ErrUnknownDimension
+		}
+		idx[j] = i
+	}
+
+	groups := make(map[string]*VecEntry)
+	vec.Range(func(values []string, v float64, c T) bool {
+		group := make([]string, len(idx))
+		for j, i := range idx {
+			group[j] = values[i]
+		}
+
+		key := vecKey(group)
+		entry, ok := groups[key]
+		if !ok {
+			entry = &VecEntry{Values: group}
+			groups[key] = entry
+		}
+		entry.Value += v
+
+		return true
+	})
+
+	entries := make([]VecEntry, 0, len(groups))
+	for _, entry := range groups {
+		entries = append(entries, *entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i].Values, entries[j].Values
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return false
+	})
+
+	return entries, nil
+}
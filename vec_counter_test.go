@@ -0,0 +1,174 @@
+package gounter
+
+import (
+	"testing"
+)
+
+func TestVecCounter(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewVecCounterNormal("method", "path", "code")
+	if err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+	if dims := c.Dims(); len(dims) != 3 || dims[0] != "method" {
+		t.Fatalf("wrong dims, got %v", dims)
+	}
+
+	requests := [][]string{
+		{"GET", "/api", "200"},
+		{"GET", "/api", "200"},
+		{"GET", "/api", "500"},
+		{"POST", "/api", "200"},
+		{"GET", "/", "200"},
+		// separators in values are kept
+		{"GET|x", "", "200"},
+	}
+	for _, values := range requests {
+		g, err := c.WithLabelValues(values...)
+		if err != nil {
+			t.Fatalf("should be nil, but %v", err)
+		}
+		g.Inc()
+	}
+
+	g, err := c.With(map[string]string{"code": "200", "path": "/api", "method": "GET"})
+	if err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+	if v := g.Get(); v != 2 {
+		t.Fatalf("should be %d, but %f", 2, v)
+	}
+
+	if n := c.Len(); n != 5 {
+		t.Fatalf("should be %d, but %d", 5, n)
+	}
+
+	sum, err := c.Sum(map[string]string{"method": "GET"})
+	if err != nil || sum != 4 {
+		t.Fatalf("should be %d, but %f, %v", 4, sum, err)
+	}
+
+	entries, err := c.Filter(map[string]string{"path": "/api", "code": "200"})
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("should be %d entries, but %v, %v", 2, entries, err)
+	}
+
+	entries, err = c.SumBy("code")
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("should be %d entries, but %v, %v", 2, entries, err)
+	}
+	if entries[0].Values[0] != "200" || entries[0].Value != 5 {
+		t.Fatalf("wrong entry, got %v", entries[0])
+	}
+	if entries[1].Values[0] != "500" || entries[1].Value != 1 {
+		t.Fatalf("wrong entry, got %v", entries[1])
+	}
+
+	if err := c.Remove("GET", "/", "200"); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+	if n := c.Len(); n != 4 {
+		t.Fatalf("should be %d, but %d", 4, n)
+	}
+
+	c.Reset()
+	if n := c.Len(); n != 0 {
+		t.Fatalf("should be %d, but %d", 0, n)
+	}
+}
+
+func TestVecCounterErrors(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewVecCounterNormal("a", "b")
+	if err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+
+	if _, err := c.WithLabelValues("x"); err != ErrLabelValuesCount {
+		t.Fatalf("should be %v, but %v", ErrLabelValuesCount, err)
+	}
+	if _, err := c.With(map[string]string{"a": "x"}); err != ErrLabelValuesCount {
+		t.Fatalf("should be %v, but %v", ErrLabelValuesCount, err)
+	}
+	if _, err := c.With(map[string]string{"a": "x", "c": "y"}); err != ErrUnknownDimension {
+		t.Fatalf("should be %v, but %v", ErrUnknownDimension, err)
+	}
+	if _, err := c.Sum(map[string]string{"c": "y"}); err != ErrUnknownDimension {
+		t.Fatalf("should be %v, but %v", ErrUnknownDimension, err)
+	}
+	if _, err := c.SumBy("c"); err != ErrUnknownDimension {
+		t.Fatalf("should be %v, but %v", ErrUnknownDimension, err)
+	}
+	if err := c.Remove(); err != ErrLabelValuesCount {
+		t.Fatalf("should be %v, but %v", ErrLabelValuesCount, err)
+	}
+}
+
+func TestVecCounterInvalidDims(t *testing.T) {
+	t.Parallel()
+
+	for _, dims := range [][]string{{"a", "a"}, {""}, {"a", "b", "a"}} {
+		if _, err := NewVecCounterNormal(dims...); err != ErrInvalidDimension {
+			t.Fatalf("%q should be %v, but %v", dims, ErrInvalidDimension, err)
+		}
+	}
+}
+
+func TestVecCounterLimit(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewVecCounterNormal("a")
+	if err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+	c.SetLabelLimit(1, LabelLimitReject)
+
+	if _, err := c.WithLabelValues("x"); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+	if _, err := c.WithLabelValues("y"); err != ErrLabelLimit {
+		t.Fatalf("should be %v, but %v", ErrLabelLimit, err)
+	}
+}
+
+func TestVecCounterLimitOverflow(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewVecCounterNormal("a", "b")
+	if err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+	c.SetLabelLimit(1, LabelLimitOverflow)
+
+	for _, v := range []string{"x", "y", "z"} {
+		g, err := c.WithLabelValues(v, v)
+		if err != nil {
+			t.Fatalf("should be nil, but %v", err)
+		}
+		g.Inc()
+	}
+
+	sum, err := c.Sum(map[string]string{"a": OverflowLabel, "b": OverflowLabel})
+	if err != nil || sum != 2 {
+		t.Fatalf("should be %d, but %f, %v", 2, sum, err)
+	}
+}
+
+func TestVecCounterClose(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewVecCounterNormal("a")
+	if err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+	c.WithLabelValues("x")
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("should be nil, but %v", err)
+	}
+	if _, err := c.WithLabelValues("x"); err != ErrLabelCounterClosed {
+		t.Fatalf("should be %v, but %v", ErrLabelCounterClosed, err)
+	}
+}